@@ -1,6 +1,10 @@
 package v1alpha1
 
 import (
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,12 +14,45 @@ import (
 // AtlasMapSpec defines the desired state of AtlasMap
 // +k8s:openapi-gen=true
 type AtlasMapSpec struct {
-	// Replicas determines the desired number of running AtlasMap pods
-	Replicas int32 `json:"replicas,omitempty"`
+	// Replicas determines the desired number of running AtlasMap pods. It is ignored when autoscaling is configured
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Autoscaling hands ownership of the number of running AtlasMap pods to a HorizontalPodAutoscaler
+	Autoscaling *AtlasMapAutoscalingSpec `json:"autoscaling,omitempty"`
+	// Exposure selects how AtlasMap is exposed outside of the cluster
+	Exposure *AtlasMapExposureSpec `json:"exposure,omitempty"`
 	// RouteHostName sets the host name to use on the Ingress or OpenShift Route
 	RouteHostName string `json:"routeHostName,omitempty"`
-	// Version sets the version of the container image used for AtlasMap
+	// Ingress configures the Ingress exposing AtlasMap, when the exposure type is Ingress
+	Ingress *AtlasMapIngressSpec `json:"ingress,omitempty"`
+	// Route configures the OpenShift Route exposing AtlasMap, when the exposure type is Route
+	Route *AtlasMapRouteSpec `json:"route,omitempty"`
+	// Service configures the Service exposing the AtlasMap pods
+	Service *AtlasMapServiceSpec `json:"service,omitempty"`
+	// Monitoring creates a Prometheus Operator ServiceMonitor and PrometheusRule for AtlasMap, when their APIs are installed
+	Monitoring *AtlasMapMonitoringSpec `json:"monitoring,omitempty"`
+	// Storage persists the AtlasMap workspace, holding mapping definitions and uploaded libraries, on a PersistentVolumeClaim
+	Storage *AtlasMapStorageSpec `json:"storage,omitempty"`
+	// PodTemplate configures the scheduling of the AtlasMap pods
+	PodTemplate *AtlasMapPodTemplateSpec `json:"podTemplate,omitempty"`
+	// Version sets the version of the container image used for AtlasMap. When the admission webhook is deployed,
+	// AtlasMaps created without a version are set to the default version of the operator
 	Version string `json:"version,omitempty"`
+	// Image sets the complete container image reference used for AtlasMap, including a tag or an @sha256 digest.
+	// It takes precedence over the image derived from version
+	Image string `json:"image,omitempty"`
+	// ImagePullPolicy sets the pull policy for the AtlasMap container image.
+	// Defaults to IfNotPresent for images pinned to a digest and Always otherwise
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// ImagePullSecrets references secrets in the AtlasMap namespace used to pull the AtlasMap container image
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Env sets environment variables of the AtlasMap container, overriding those set by the operator
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// EnvFrom sets environment variables of the AtlasMap container from ConfigMaps and Secrets
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+	// ApplicationProperties are Spring Boot properties passed to AtlasMap, e.g. logging.level.io.atlasmap: DEBUG
+	ApplicationProperties map[string]string `json:"applicationProperties,omitempty"`
 	// The amount of CPU to request
 	// +kubebuilder:validation:Pattern=[0-9]+m?$
 	RequestCPU string `json:"requestCPU,omitempty"`
@@ -30,6 +67,161 @@ type AtlasMapSpec struct {
 	LimitMemory string `json:"limitMemory,omitempty"`
 }
 
+// AtlasMapAutoscalingSpec defines the bounds within which AtlasMap pods are scaled
+type AtlasMapAutoscalingSpec struct {
+	// The lower limit for the number of AtlasMap pods. Defaults to 1
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// The upper limit for the number of AtlasMap pods
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// The average CPU utilization to scale on, as a percentage of requestCPU. Defaults to 80 when no memory target is set
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// The average memory utilization to scale on, as a percentage of requestMemory
+	// +kubebuilder:validation:Minimum=1
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+}
+
+// AtlasMapExposureSpec defines how AtlasMap is exposed outside of the cluster
+type AtlasMapExposureSpec struct {
+	// The kind of resource exposing AtlasMap. Defaults to Route on OpenShift and Ingress otherwise.
+	// None keeps AtlasMap internal to the cluster
+	// +kubebuilder:validation:Enum=Route;Ingress;GatewayHTTPRoute;LoadBalancer;None
+	Type AtlasMapExposureType `json:"type,omitempty"`
+	// Gateway references the Gateway API Gateway that the HTTPRoute attaches to. Required when the exposure type is GatewayHTTPRoute
+	Gateway *AtlasMapGatewayReference `json:"gateway,omitempty"`
+}
+
+// AtlasMapGatewayReference identifies the parent Gateway, and optionally one of its listeners, of the AtlasMap HTTPRoute
+type AtlasMapGatewayReference struct {
+	// The name of the Gateway
+	Name string `json:"name"`
+	// The namespace of the Gateway. Defaults to the namespace of the AtlasMap
+	Namespace string `json:"namespace,omitempty"`
+	// The name of the Gateway listener to attach to. Defaults to every listener that allows the HTTPRoute
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// AtlasMapExposureType --
+type AtlasMapExposureType string
+
+const (
+	// AtlasMapExposureRoute --
+	AtlasMapExposureRoute AtlasMapExposureType = "Route"
+	// AtlasMapExposureIngress --
+	AtlasMapExposureIngress AtlasMapExposureType = "Ingress"
+	// AtlasMapExposureGatewayHTTPRoute --
+	AtlasMapExposureGatewayHTTPRoute AtlasMapExposureType = "GatewayHTTPRoute"
+	// AtlasMapExposureLoadBalancer --
+	AtlasMapExposureLoadBalancer AtlasMapExposureType = "LoadBalancer"
+	// AtlasMapExposureNone --
+	AtlasMapExposureNone AtlasMapExposureType = "None"
+)
+
+// AtlasMapIngressSpec defines how AtlasMap is exposed by its Ingress
+type AtlasMapIngressSpec struct {
+	// The name of the IngressClass implementing the Ingress. Defaults to the default IngressClass of the cluster
+	ClassName string `json:"className,omitempty"`
+	// The name of the Secret holding the TLS certificate for the Ingress host. AtlasMap is served over HTTPS when set
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// Annotations added to the Ingress, e.g. to configure the ingress controller
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// The path under which AtlasMap is served. Defaults to /
+	// +kubebuilder:validation:Pattern=^/
+	Path string `json:"path,omitempty"`
+	// The way the path is matched. Defaults to Prefix
+	// +kubebuilder:validation:Enum=Exact;Prefix;ImplementationSpecific
+	PathType netv1.PathType `json:"pathType,omitempty"`
+}
+
+// AtlasMapRouteSpec defines how AtlasMap is exposed by its OpenShift Route
+type AtlasMapRouteSpec struct {
+	// TLS configures how the Route secures connections to AtlasMap
+	TLS *AtlasMapRouteTLSSpec `json:"tls,omitempty"`
+}
+
+// AtlasMapRouteTLSSpec defines the TLS termination of the AtlasMap Route
+type AtlasMapRouteTLSSpec struct {
+	// Where TLS is terminated. With reencrypt and passthrough, AtlasMap serves HTTPS using an OpenShift service serving certificate.
+	// Defaults to edge
+	// +kubebuilder:validation:Enum=edge;reencrypt;passthrough
+	Termination routev1.TLSTerminationType `json:"termination,omitempty"`
+	// How the Route handles insecure HTTP connections. Defaults to None, which rejects them
+	// +kubebuilder:validation:Enum=None;Allow;Redirect
+	InsecureEdgeTerminationPolicy routev1.InsecureEdgeTerminationPolicyType `json:"insecureEdgeTerminationPolicy,omitempty"`
+	// The name of a Secret in the AtlasMap namespace holding the certificate (tls.crt), key (tls.key) and,
+	// optionally, the CA certificate (ca.crt) that the Route presents to clients. Defaults to the router certificate.
+	// Not supported with passthrough termination
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+}
+
+// AtlasMapServiceSpec defines how the AtlasMap pods are exposed by their Service
+type AtlasMapServiceSpec struct {
+	// The type of the Service. Defaults to ClusterIP. It is ignored when the exposure type is LoadBalancer or None
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// Annotations added to the Service, e.g. to configure a cloud provider load balancer
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Jolokia publishes the Jolokia port (8778) of the AtlasMap pods. Defaults to true
+	Jolokia *bool `json:"jolokia,omitempty"`
+	// Prometheus publishes the Prometheus metrics port (9779) of the AtlasMap pods. Defaults to true
+	Prometheus *bool `json:"prometheus,omitempty"`
+}
+
+// AtlasMapMonitoringSpec defines how Prometheus scrapes and alerts on AtlasMap
+type AtlasMapMonitoringSpec struct {
+	// The interval at which Prometheus scrapes the AtlasMap pods. Defaults to 30s
+	// +kubebuilder:validation:Pattern=^([0-9]+(ms|s|m|h))+$
+	Interval string `json:"interval,omitempty"`
+	// Labels added to the ServiceMonitor and PrometheusRule, e.g. to match the selectors of a Prometheus instance
+	Labels map[string]string `json:"labels,omitempty"`
+	// Alerts creates a PrometheusRule with default availability and restart alerts. Defaults to true
+	Alerts *bool `json:"alerts,omitempty"`
+}
+
+// AtlasMapStorageSpec defines the PersistentVolumeClaim holding the AtlasMap workspace
+type AtlasMapStorageSpec struct {
+	// The size of the PersistentVolumeClaim created by the operator. Required unless claimName is set
+	Size *resource.Quantity `json:"size,omitempty"`
+	// The StorageClass of the PersistentVolumeClaim created by the operator. Defaults to the default StorageClass of the cluster
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// The access modes of the PersistentVolumeClaim created by the operator. Defaults to ReadWriteOnce.
+	// ReadWriteMany is required when more than one replica may run
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// The name of an existing PersistentVolumeClaim in the AtlasMap namespace to use instead of creating one
+	ClaimName string `json:"claimName,omitempty"`
+}
+
+// AtlasMapPodTemplateSpec defines where the AtlasMap pods are scheduled
+type AtlasMapPodTemplateSpec struct {
+	// Labels that nodes must have to run AtlasMap pods
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations allowing AtlasMap pods to run on tainted nodes
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity constraints of the AtlasMap pods. When not set and more than one replica may run,
+	// AtlasMap pods prefer to run on different nodes
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// How AtlasMap pods are spread across topology domains such as zones
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// The PriorityClass of the AtlasMap pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// The RuntimeClass used to run the AtlasMap pods
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+}
+
+// AtlasMapAutoscalingStatus defines the observed state of the AtlasMap HorizontalPodAutoscaler
+type AtlasMapAutoscalingStatus struct {
+	// The lower limit for the number of AtlasMap pods
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+	// The upper limit for the number of AtlasMap pods
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+	// The number of AtlasMap pods as last seen by the autoscaler
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+	// The number of AtlasMap pods the autoscaler last calculated as desired
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+}
+
 // AtlasMapStatus defines the observed state of AtlasMap
 // +k8s:openapi-gen=true
 type AtlasMapStatus struct {
@@ -37,8 +229,24 @@ type AtlasMapStatus struct {
 	URL string `json:"URL,omitempty"`
 	// The container image that AtlasMap is using
 	Image string `json:"image,omitempty"`
+	// The AtlasMap version deployed, which is the operator default version when no version is set
+	Version string `json:"version,omitempty"`
 	// The current phase that the AtlasMap resource is in
 	Phase AtlasMapPhase `json:"phase,omitempty"`
+	// The number of AtlasMap pods targeted by the deployment
+	Replicas int32 `json:"replicas,omitempty"`
+	// The label selector for AtlasMap pods, used by the scale subresource
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// The state of the HorizontalPodAutoscaler when autoscaling is configured
+	Autoscaling *AtlasMapAutoscalingStatus `json:"autoscaling,omitempty"`
+	// The most recent AtlasMap generation that has been fully reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The latest available observations of the AtlasMap state
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
@@ -49,6 +257,7 @@ type AtlasMapStatus struct {
 // +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.labelSelector
 // +kubebuilder:printcolumn:name="URL",description=AtlasMap URL,type=string,JSONPath=`.status.URL`
 // +kubebuilder:printcolumn:name="Image",description=AtlasMap image,type=string,JSONPath=`.status.image`
+// +kubebuilder:printcolumn:name="Version",description=AtlasMap version,type=string,JSONPath=`.status.version`,priority=1
 // +kubebuilder:printcolumn:name="Phase",description=AtlasMap phase,type=string,JSONPath=`.status.phase`
 type AtlasMap struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -79,6 +288,27 @@ const (
 	AtlasMapPhasePhaseDeployed AtlasMapPhase = "Deployed"
 )
 
+const (
+	// AtlasMapConditionReady --
+	AtlasMapConditionReady = "Ready"
+	// AtlasMapConditionAvailable --
+	AtlasMapConditionAvailable = "Available"
+	// AtlasMapConditionProgressing --
+	AtlasMapConditionProgressing = "Progressing"
+	// AtlasMapConditionDegraded --
+	AtlasMapConditionDegraded = "Degraded"
+	// AtlasMapConditionExposureReady --
+	AtlasMapConditionExposureReady = "ExposureReady"
+	// AtlasMapConditionAutoscalingReady --
+	AtlasMapConditionAutoscalingReady = "AutoscalingReady"
+	// AtlasMapConditionConfigReady --
+	AtlasMapConditionConfigReady = "ConfigReady"
+	// AtlasMapConditionVersionSupported --
+	AtlasMapConditionVersionSupported = "VersionSupported"
+	// AtlasMapConditionStorageReady --
+	AtlasMapConditionStorageReady = "StorageReady"
+)
+
 func init() {
 	SchemeBuilder.Register(&AtlasMap{}, &AtlasMapList{})
 }
@@ -0,0 +1,230 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"regexp"
+
+	"github.com/Masterminds/semver"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// DefaultReplicas is the number of AtlasMap pods used when spec.replicas is not set
+const DefaultReplicas int32 = 1
+
+// LatestVersion is the version that selects the most recent AtlasMap image
+const LatestVersion = "latest"
+
+// DefaultVersion is the AtlasMap version set on resources created without one, which is accepted even if it is
+// not a semantic version. The operator overrides it with the version of its configured default image.
+var DefaultVersion = LatestVersion
+
+var atlasmaplog = logf.Log.WithName("atlasmap-resource")
+
+// imageReferenceRegexp matches a container image reference, with an optional registry, tag and digest
+var imageReferenceRegexp = regexp.MustCompile(`^([a-zA-Z0-9.-]+(:[0-9]+)?/)?[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*(:[\w][\w.-]{0,127})?(@[a-z0-9]+([+._-][a-z0-9]+)*:[a-fA-F0-9]{32,})?$`)
+
+// SetupWebhookWithManager registers the AtlasMap defaulting and validating webhooks with the manager
+func (r *AtlasMap) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-atlasmap-io-v1alpha1-atlasmap,mutating=true,failurePolicy=fail,sideEffects=None,groups=atlasmap.io,resources=atlasmaps,verbs=create,versions=v1alpha1,name=matlasmap.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &AtlasMap{}
+
+// Default implements webhook.Defaulter so that users see the effective version and replicas. It only runs when
+// an AtlasMap is created, so that AtlasMaps created before the webhook was deployed are not pinned to a version.
+func (r *AtlasMap) Default() {
+	atlasmaplog.Info("default", "name", r.Name)
+
+	if len(r.Spec.Version) == 0 {
+		r.Spec.Version = DefaultVersion
+	}
+
+	if r.Spec.Replicas == nil {
+		replicas := DefaultReplicas
+		r.Spec.Replicas = &replicas
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-atlasmap-io-v1alpha1-atlasmap,mutating=false,failurePolicy=fail,sideEffects=None,groups=atlasmap.io,resources=atlasmaps,verbs=create;update,versions=v1alpha1,name=vatlasmap.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &AtlasMap{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *AtlasMap) ValidateCreate() error {
+	atlasmaplog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *AtlasMap) ValidateUpdate(old runtime.Object) error {
+	atlasmaplog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *AtlasMap) ValidateDelete() error {
+	return nil
+}
+
+func (r *AtlasMap) validate() error {
+	var allErrs field.ErrorList
+	spec := field.NewPath("spec")
+
+	// The operator default version may be any tag of its configured image
+	if version := r.Spec.Version; len(version) > 0 && version != LatestVersion && version != DefaultVersion {
+		if _, err := semver.NewVersion(version); err != nil {
+			allErrs = append(allErrs, field.Invalid(spec.Child("version"), version, "must be '"+LatestVersion+"' or a semantic version"))
+		}
+	}
+
+	if image := r.Spec.Image; len(image) > 0 && !imageReferenceRegexp.MatchString(image) {
+		allErrs = append(allErrs, field.Invalid(spec.Child("image"), image, "must be a valid container image reference"))
+	}
+
+	if host := r.Spec.RouteHostName; len(host) > 0 {
+		for _, msg := range validation.IsDNS1123Subdomain(host) {
+			allErrs = append(allErrs, field.Invalid(spec.Child("routeHostName"), host, msg))
+		}
+	}
+
+	if replicas := r.Spec.Replicas; replicas != nil && *replicas < 0 {
+		allErrs = append(allErrs, field.Invalid(spec.Child("replicas"), *replicas, validation.InclusiveRangeError(0, 1<<31-1)))
+	}
+
+	if autoscaling := r.Spec.Autoscaling; autoscaling != nil && autoscaling.MinReplicas != nil && *autoscaling.MinReplicas > autoscaling.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(spec.Child("autoscaling", "minReplicas"), *autoscaling.MinReplicas, "must be less than or equal to maxReplicas"))
+	}
+
+	if storage := r.Spec.Storage; storage != nil && len(storage.ClaimName) == 0 {
+		if storage.Size == nil {
+			allErrs = append(allErrs, field.Required(spec.Child("storage", "size"), "must be set unless claimName is set"))
+		}
+		if r.maxReplicas() > 1 && !containsAccessMode(storage.AccessModes, corev1.ReadWriteMany) {
+			allErrs = append(allErrs, field.Invalid(spec.Child("storage", "accessModes"), storage.AccessModes, "must include ReadWriteMany when more than one replica may run"))
+		}
+	}
+
+	// The operator appends the workspace location to the Java options, which it cannot do to a value from a reference
+	if r.Spec.Storage != nil {
+		for i, env := range r.Spec.Env {
+			if env.Name == "JAVA_OPTIONS" && env.ValueFrom != nil {
+				allErrs = append(allErrs, field.Invalid(spec.Child("env").Index(i).Child("valueFrom"), env.Name, "must not be set for JAVA_OPTIONS when storage is configured"))
+			}
+		}
+	}
+
+	if exposure := r.Spec.Exposure; exposure != nil && exposure.Type == AtlasMapExposureGatewayHTTPRoute && (exposure.Gateway == nil || len(exposure.Gateway.Name) == 0) {
+		allErrs = append(allErrs, field.Required(spec.Child("exposure", "gateway", "name"), "must be set when exposure type is GatewayHTTPRoute"))
+	}
+
+	if route := r.Spec.Route; route != nil && route.TLS != nil && route.TLS.Termination == routev1.TLSTerminationPassthrough {
+		tls := spec.Child("route", "tls")
+		if len(route.TLS.CertificateSecretName) > 0 {
+			allErrs = append(allErrs, field.Invalid(tls.Child("certificateSecretName"), route.TLS.CertificateSecretName, "must not be set with passthrough termination"))
+		}
+		if route.TLS.InsecureEdgeTerminationPolicy == routev1.InsecureEdgeTerminationPolicyAllow {
+			allErrs = append(allErrs, field.Invalid(tls.Child("insecureEdgeTerminationPolicy"), route.TLS.InsecureEdgeTerminationPolicy, "must be None or Redirect with passthrough termination"))
+		}
+	}
+
+	if exposure, service := r.Spec.Exposure, r.Spec.Service; exposure != nil && service != nil && len(service.Type) > 0 {
+		switch {
+		case exposure.Type == AtlasMapExposureNone && service.Type != corev1.ServiceTypeClusterIP:
+			allErrs = append(allErrs, field.Invalid(spec.Child("service", "type"), service.Type, "must be ClusterIP when exposure type is None"))
+		case exposure.Type == AtlasMapExposureLoadBalancer && service.Type != corev1.ServiceTypeLoadBalancer:
+			allErrs = append(allErrs, field.Invalid(spec.Child("service", "type"), service.Type, "must be LoadBalancer when exposure type is LoadBalancer"))
+		}
+	}
+
+	if service := r.Spec.Service; r.Spec.Monitoring != nil && service != nil && service.Prometheus != nil && !*service.Prometheus {
+		allErrs = append(allErrs, field.Invalid(spec.Child("service", "prometheus"), *service.Prometheus, "must be true when monitoring is configured"))
+	}
+
+	allErrs = append(allErrs, validateRequestLimit(spec, "requestCPU", r.Spec.RequestCPU, "limitCPU", r.Spec.LimitCPU)...)
+	allErrs = append(allErrs, validateRequestLimit(spec, "requestMemory", r.Spec.RequestMemory, "limitMemory", r.Spec.LimitMemory)...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "AtlasMap"}, r.Name, allErrs)
+}
+
+// maxReplicas is the largest number of AtlasMap pods that may run
+func (r *AtlasMap) maxReplicas() int32 {
+	if autoscaling := r.Spec.Autoscaling; autoscaling != nil {
+		return autoscaling.MaxReplicas
+	}
+	if replicas := r.Spec.Replicas; replicas != nil {
+		return *replicas
+	}
+	return DefaultReplicas
+}
+
+func containsAccessMode(modes []corev1.PersistentVolumeAccessMode, mode corev1.PersistentVolumeAccessMode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRequestLimit(spec *field.Path, requestField string, request string, limitField string, limit string) field.ErrorList {
+	var allErrs field.ErrorList
+
+	requestQuantity, err := parseQuantity(request)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(spec.Child(requestField), request, err.Error()))
+	}
+
+	limitQuantity, err := parseQuantity(limit)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(spec.Child(limitField), limit, err.Error()))
+	}
+
+	if requestQuantity != nil && limitQuantity != nil && requestQuantity.Cmp(*limitQuantity) > 0 {
+		allErrs = append(allErrs, field.Invalid(spec.Child(requestField), request, "must be less than or equal to "+limitField))
+	}
+
+	return allErrs
+}
+
+func parseQuantity(value string) (*resource.Quantity, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, err
+	}
+	return &quantity, nil
+}
@@ -0,0 +1,97 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefault(t *testing.T) {
+	atlasMap := &AtlasMap{}
+	atlasMap.Default()
+	assert.Equal(t, DefaultVersion, atlasMap.Spec.Version)
+	assert.Equal(t, DefaultReplicas, *atlasMap.Spec.Replicas)
+
+	replicas := int32(0)
+	atlasMap = &AtlasMap{Spec: AtlasMapSpec{Version: "2.3.0", Replicas: &replicas}}
+	atlasMap.Default()
+	assert.Equal(t, "2.3.0", atlasMap.Spec.Version)
+	assert.Equal(t, int32(0), *atlasMap.Spec.Replicas)
+}
+
+func TestValidate(t *testing.T) {
+	int32Ptr := func(value int32) *int32 {
+		return &value
+	}
+	boolPtr := func(value bool) *bool {
+		return &value
+	}
+	quantityPtr := func(value string) *resource.Quantity {
+		quantity := resource.MustParse(value)
+		return &quantity
+	}
+
+	tests := []struct {
+		name           string
+		defaultVersion string
+		spec           AtlasMapSpec
+		valid          bool
+	}{
+		{name: "empty spec", spec: AtlasMapSpec{}, valid: true},
+		{name: "latest version", spec: AtlasMapSpec{Version: "latest"}, valid: true},
+		{name: "semantic version", spec: AtlasMapSpec{Version: "2.3.0-SNAPSHOT"}, valid: true},
+		{name: "invalid version", spec: AtlasMapSpec{Version: "1.x"}},
+		{name: "operator default version", defaultVersion: "nightly", spec: AtlasMapSpec{Version: "nightly"}, valid: true},
+		{name: "image", spec: AtlasMapSpec{Image: "docker.io/atlasmap/atlasmap:2.3.0"}, valid: true},
+		{name: "image digest", spec: AtlasMapSpec{Image: "registry.local:5000/atlasmap@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}, valid: true},
+		{name: "invalid image", spec: AtlasMapSpec{Image: "docker.io/AtlasMap:latest tag"}},
+		{name: "route host name", spec: AtlasMapSpec{RouteHostName: "atlasmap.example.com"}, valid: true},
+		{name: "invalid route host name", spec: AtlasMapSpec{RouteHostName: "AtlasMap_Example"}},
+		{name: "monitoring", spec: AtlasMapSpec{Monitoring: &AtlasMapMonitoringSpec{}}, valid: true},
+		{name: "monitoring without prometheus port", spec: AtlasMapSpec{Monitoring: &AtlasMapMonitoringSpec{}, Service: &AtlasMapServiceSpec{Prometheus: boolPtr(false)}}},
+		{name: "internal exposure", spec: AtlasMapSpec{Exposure: &AtlasMapExposureSpec{Type: AtlasMapExposureNone}, Service: &AtlasMapServiceSpec{Type: corev1.ServiceTypeClusterIP}}, valid: true},
+		{name: "internal exposure with node port service", spec: AtlasMapSpec{Exposure: &AtlasMapExposureSpec{Type: AtlasMapExposureNone}, Service: &AtlasMapServiceSpec{Type: corev1.ServiceTypeNodePort}}},
+		{name: "load balancer exposure with cluster IP service", spec: AtlasMapSpec{Exposure: &AtlasMapExposureSpec{Type: AtlasMapExposureLoadBalancer}, Service: &AtlasMapServiceSpec{Type: corev1.ServiceTypeClusterIP}}},
+		{name: "HTTPRoute exposure without gateway", spec: AtlasMapSpec{Exposure: &AtlasMapExposureSpec{Type: AtlasMapExposureGatewayHTTPRoute}}},
+		{name: "HTTPRoute exposure", spec: AtlasMapSpec{Exposure: &AtlasMapExposureSpec{Type: AtlasMapExposureGatewayHTTPRoute, Gateway: &AtlasMapGatewayReference{Name: "gateway"}}}, valid: true},
+		{name: "passthrough route redirecting HTTP", spec: AtlasMapSpec{Route: &AtlasMapRouteSpec{TLS: &AtlasMapRouteTLSSpec{Termination: routev1.TLSTerminationPassthrough, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect}}}, valid: true},
+		{name: "passthrough route allowing HTTP", spec: AtlasMapSpec{Route: &AtlasMapRouteSpec{TLS: &AtlasMapRouteTLSSpec{Termination: routev1.TLSTerminationPassthrough, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyAllow}}}},
+		{name: "passthrough route with certificate", spec: AtlasMapSpec{Route: &AtlasMapRouteSpec{TLS: &AtlasMapRouteTLSSpec{Termination: routev1.TLSTerminationPassthrough, CertificateSecretName: "atlasmap-tls"}}}},
+		{name: "storage size", spec: AtlasMapSpec{Storage: &AtlasMapStorageSpec{Size: quantityPtr("1Gi")}}, valid: true},
+		{name: "existing storage claim", spec: AtlasMapSpec{Storage: &AtlasMapStorageSpec{ClaimName: "atlasmap-data"}}, valid: true},
+		{name: "storage without size or claim", spec: AtlasMapSpec{Storage: &AtlasMapStorageSpec{}}},
+		{name: "single writer storage with replicas", spec: AtlasMapSpec{Replicas: int32Ptr(2), Storage: &AtlasMapStorageSpec{Size: quantityPtr("1Gi")}}},
+		{name: "shared storage with replicas", spec: AtlasMapSpec{Replicas: int32Ptr(2), Storage: &AtlasMapStorageSpec{Size: quantityPtr("1Gi"), AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}}}, valid: true},
+		{name: "single writer storage with autoscaling", spec: AtlasMapSpec{Autoscaling: &AtlasMapAutoscalingSpec{MaxReplicas: 3}, Storage: &AtlasMapStorageSpec{Size: quantityPtr("1Gi")}}},
+		{name: "JAVA_OPTIONS value with storage", spec: AtlasMapSpec{Env: []corev1.EnvVar{{Name: "JAVA_OPTIONS", Value: "-Xmx1g"}}, Storage: &AtlasMapStorageSpec{Size: quantityPtr("1Gi")}}, valid: true},
+		{name: "JAVA_OPTIONS reference", spec: AtlasMapSpec{Env: []corev1.EnvVar{{Name: "JAVA_OPTIONS", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "options"}}}}}, valid: true},
+		{name: "JAVA_OPTIONS reference with storage", spec: AtlasMapSpec{Env: []corev1.EnvVar{{Name: "JAVA_OPTIONS", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "options"}}}}, Storage: &AtlasMapStorageSpec{Size: quantityPtr("1Gi")}}},
+		{name: "negative replicas", spec: AtlasMapSpec{Replicas: int32Ptr(-1)}},
+		{name: "CPU request within limit", spec: AtlasMapSpec{RequestCPU: "200m", LimitCPU: "300m"}, valid: true},
+		{name: "CPU request above limit", spec: AtlasMapSpec{RequestCPU: "1", LimitCPU: "300m"}},
+		{name: "memory request within limit", spec: AtlasMapSpec{RequestMemory: "256Mi", LimitMemory: "1Gi"}, valid: true},
+		{name: "memory request above limit", spec: AtlasMapSpec{RequestMemory: "2Gi", LimitMemory: "512Mi"}},
+		{name: "autoscaling minimum above maximum", spec: AtlasMapSpec{Autoscaling: &AtlasMapAutoscalingSpec{MinReplicas: int32Ptr(3), MaxReplicas: 2}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if len(test.defaultVersion) > 0 {
+				defaultVersion := DefaultVersion
+				t.Cleanup(func() { DefaultVersion = defaultVersion })
+				DefaultVersion = test.defaultVersion
+			}
+			atlasMap := &AtlasMap{ObjectMeta: v1.ObjectMeta{Name: "test"}, Spec: test.spec}
+			if test.valid {
+				assert.NoError(t, atlasMap.ValidateCreate())
+				assert.NoError(t, atlasMap.ValidateUpdate(nil))
+			} else {
+				assert.Error(t, atlasMap.ValidateCreate())
+				assert.Error(t, atlasMap.ValidateUpdate(nil))
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AtlasMappingSpec defines the desired state of AtlasMapping
+// +k8s:openapi-gen=true
+type AtlasMappingSpec struct {
+	// AtlasMapName is the name of the AtlasMap, in the namespace of the AtlasMapping, that the mapping is loaded into
+	// +kubebuilder:validation:MinLength=1
+	AtlasMapName string `json:"atlasMapName"`
+	// Format of the mapping content: an ADM archive exported from the AtlasMap UI, or a mapping definition in JSON.
+	// Defaults to ADM
+	// +kubebuilder:validation:Enum=ADM;JSON
+	Format AtlasMappingFormat `json:"format,omitempty"`
+	// DefinitionID is the identifier of the mapping definition in AtlasMap. Defaults to 0, the mapping opened by the AtlasMap UI
+	// +kubebuilder:validation:Minimum=0
+	DefinitionID int32 `json:"definitionID,omitempty"`
+	// Source holds the mapping content. Exactly one of its fields must be set
+	Source AtlasMappingSource `json:"source"`
+}
+
+// AtlasMappingSource selects where the mapping content is read from
+type AtlasMappingSource struct {
+	// ConfigMap selects a key of a ConfigMap in the AtlasMapping namespace. ADM archives are read from its binaryData
+	ConfigMap *AtlasMappingKeySelector `json:"configMap,omitempty"`
+	// Secret selects a key of a Secret in the AtlasMapping namespace
+	Secret *AtlasMappingKeySelector `json:"secret,omitempty"`
+	// PersistentVolumeClaim selects a file on a PersistentVolumeClaim in the AtlasMapping namespace. The file is
+	// loaded by a Job mounting the claim, which runs curl from the mapping loader image configured on the operator
+	PersistentVolumeClaim *AtlasMappingVolumeSource `json:"persistentVolumeClaim,omitempty"`
+}
+
+// AtlasMappingKeySelector selects a key of a ConfigMap or Secret
+type AtlasMappingKeySelector struct {
+	// The name of the ConfigMap or Secret
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// The key holding the mapping content
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+}
+
+// AtlasMappingVolumeSource selects a file on a PersistentVolumeClaim
+type AtlasMappingVolumeSource struct {
+	// The name of the PersistentVolumeClaim
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+	// The path of the mapping file, relative to the root of the volume
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+}
+
+// AtlasMappingPodStatus identifies an AtlasMap pod, and the container restart, that a mapping has been loaded into
+type AtlasMappingPodStatus struct {
+	// The name of the pod
+	Name string `json:"name"`
+	// The UID of the pod
+	UID types.UID `json:"uid"`
+	// The restart count of the AtlasMap container when the mapping was loaded
+	RestartCount int32 `json:"restartCount,omitempty"`
+}
+
+// AtlasMappingStatus defines the observed state of AtlasMapping
+// +k8s:openapi-gen=true
+type AtlasMappingStatus struct {
+	// The hash of the mapping content and settings that has been loaded
+	ContentHash string `json:"contentHash,omitempty"`
+	// The AtlasMap pods that the mapping has been loaded into
+	Pods []AtlasMappingPodStatus `json:"pods,omitempty"`
+	// The last time the mapping was loaded into an AtlasMap pod
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// The most recent AtlasMapping generation that has been fully synced
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The latest available observations of the AtlasMapping state
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +kubebuilder:object:root=true
+
+// AtlasMapping is the Schema for the atlasmappings API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="AtlasMap",description=AtlasMap name,type=string,JSONPath=`.spec.atlasMapName`
+// +kubebuilder:printcolumn:name="Format",description=Mapping format,type=string,JSONPath=`.spec.format`
+// +kubebuilder:printcolumn:name="Synced",description=Whether the mapping is loaded,type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+type AtlasMapping struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AtlasMappingSpec   `json:"spec,omitempty"`
+	Status AtlasMappingStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AtlasMappingList contains a list of AtlasMapping
+type AtlasMappingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AtlasMapping `json:"items"`
+}
+
+// AtlasMappingFormat --
+type AtlasMappingFormat string
+
+const (
+	// AtlasMappingFormatADM --
+	AtlasMappingFormatADM AtlasMappingFormat = "ADM"
+	// AtlasMappingFormatJSON --
+	AtlasMappingFormatJSON AtlasMappingFormat = "JSON"
+)
+
+const (
+	// AtlasMappingConditionSynced --
+	AtlasMappingConditionSynced = "Synced"
+)
+
+// MappingFormat returns the format of the mapping content, defaulting to ADM
+func (m *AtlasMapping) MappingFormat() AtlasMappingFormat {
+	if len(m.Spec.Format) > 0 {
+		return m.Spec.Format
+	}
+	return AtlasMappingFormatADM
+}
+
+func init() {
+	SchemeBuilder.Register(&AtlasMapping{}, &AtlasMappingList{})
+}
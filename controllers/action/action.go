@@ -3,70 +3,84 @@ package action
 import (
 	"context"
 
-	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/metrics"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 type Action interface {
+	// Handle reconciles the resources of the AtlasMap and records the outcome in its status,
+	// which is written by the controller once every action ran
 	Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error
 	GetName() string
 }
 
 type baseAction struct {
-	log    logr.Logger
-	client client.Client
-	scheme *runtime.Scheme
-	config *rest.Config
-	name   string
+	log          logr.Logger
+	client       client.Client
+	scheme       *runtime.Scheme
+	config       *rest.Config
+	recorder     record.EventRecorder
+	capabilities *capabilities.Detector
+	name         string
 }
 
+// Reasons of the events recorded on AtlasMaps
+const (
+	eventReasonCreated            = "Created"
+	eventReasonDeleted            = "Deleted"
+	eventReasonCreateFailed       = "CreateFailed"
+	eventReasonUpdateFailed       = "UpdateFailed"
+	eventReasonPhaseChanged       = "PhaseChanged"
+	eventReasonImageUpdated       = "ImageUpdated"
+	eventReasonReplicasUpdated    = "ReplicasUpdated"
+	eventReasonUnsupportedVersion = "UnsupportedVersion"
+	eventReasonConsoleLinkFailed  = "ConsoleLinkFailed"
+	eventReasonResourceConflict   = "ResourceConflict"
+)
+
 /*
  * Create new operator actions
  */
-func NewOperatorActions(log logr.Logger, mgr manager.Manager) []Action {
-	isOpenShift, err := util.IsOpenShift(mgr.GetConfig())
-	if err != nil {
-		log.Error(err, "Failed to determine cluster version. Defaulting to Kubernetes mode.")
-	}
-
-	var consoleLinkAction, routeAction Action
-	if isOpenShift {
-		routeAction = newRouteAction(log.WithValues("type", "create-route"), mgr)
-
-		if util.IsOpenShift43Plus(mgr.GetConfig()) {
-			consoleLinkAction = newConsoleLinkAction(log.WithValues("type", "create-consolelink"), mgr)
-		}
-	} else {
-		routeAction = newIngressAction(log.WithValues("type", "create-ingress"), mgr)
+func NewOperatorActions(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) []Action {
+	// Every exposure action removes its resources when another exposure type is selected. The ConsoleLink
+	// and monitoring actions do nothing unless the cluster serves the corresponding APIs
+	return []Action{
+		newServiceAction(log.WithValues("type", "service"), mgr, capabilities),
+		newRouteAction(log.WithValues("type", "create-route"), mgr, capabilities),
+		newIngressAction(log.WithValues("type", "create-ingress"), mgr, capabilities),
+		newHTTPRouteAction(log.WithValues("type", "create-httproute"), mgr, capabilities),
+		newConfigMapAction(log.WithValues("type", "configmap"), mgr, capabilities),
+		newStorageAction(log.WithValues("type", "storage"), mgr, capabilities),
+		newDeploymentAction(log.WithValues("type", "create-deployment"), mgr, capabilities),
+		newAutoscalerAction(log.WithValues("type", "autoscaler"), mgr, capabilities),
+		newConsoleLinkAction(log.WithValues("type", "create-consolelink"), mgr, capabilities),
+		newMonitoringAction(log.WithValues("type", "monitoring"), mgr, capabilities),
 	}
-
-	actions := []Action{
-		newServiceAction(log.WithValues("type", "service"), mgr),
-		routeAction,
-		newDeploymentAction(log.WithValues("type", "create-deployment"), mgr),
-	}
-
-	if consoleLinkAction != nil {
-		actions = append(actions, consoleLinkAction)
-	}
-
-	return actions
 }
 
-func newBaseAction(log logr.Logger, mgr manager.Manager, name string) baseAction {
+func newBaseAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector, name string) baseAction {
 	return baseAction{
 		log,
-		mgr.GetClient(),
+		metrics.NewCountingClient(mgr.GetClient()),
 		mgr.GetScheme(),
 		mgr.GetConfig(),
+		mgr.GetEventRecorderFor("atlasmap-operator"),
+		capabilities,
 		name,
 	}
 }
@@ -79,15 +93,95 @@ func (action *baseAction) deployResource(ctx context.Context, atlasMap *v1alpha1
 	if err := controllerutil.SetControllerReference(atlasMap, resource.(v1.Object), action.scheme); err != nil {
 		return err
 	}
-	return action.client.Create(ctx, resource)
+	if err := action.client.Create(ctx, resource); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			action.recorder.Eventf(atlasMap, corev1.EventTypeWarning, eventReasonCreateFailed, "Failed to create %s %s: %v", action.kindOf(resource), resource.GetName(), err)
+		}
+		return err
+	}
+	action.recorder.Eventf(atlasMap, corev1.EventTypeNormal, eventReasonCreated, "Created %s %s", action.kindOf(resource), resource.GetName())
+	return nil
+}
+
+// updateResource updates a resource of the AtlasMap and records a warning event if that fails.
+// Conflicts are not reported since the AtlasMap is reconciled again.
+func (action *baseAction) updateResource(ctx context.Context, atlasMap *v1alpha1.AtlasMap, resource client.Object) error {
+	if err := action.client.Update(ctx, resource); err != nil {
+		if !errors.IsConflict(err) {
+			action.recorder.Eventf(atlasMap, corev1.EventTypeWarning, eventReasonUpdateFailed, "Failed to update %s %s: %v", action.kindOf(resource), resource.GetName(), err)
+		}
+		return err
+	}
+	return nil
+}
+
+// exposureType resolves how the AtlasMap is exposed, a Route on OpenShift and an Ingress otherwise by default
+func (action *baseAction) exposureType(atlasMap *v1alpha1.AtlasMap) v1alpha1.AtlasMapExposureType {
+	if exposure := atlasMap.Spec.Exposure; exposure != nil && len(exposure.Type) > 0 {
+		return exposure.Type
+	}
+	if action.capabilities.Get().Routes {
+		return v1alpha1.AtlasMapExposureRoute
+	}
+	return v1alpha1.AtlasMapExposureIngress
+}
+
+// servesTLS tells whether the AtlasMap pods serve HTTPS, which the Route requires for reencrypt and passthrough termination
+func (action *baseAction) servesTLS(atlasMap *v1alpha1.AtlasMap) bool {
+	if action.exposureType(atlasMap) != v1alpha1.AtlasMapExposureRoute {
+		return false
+	}
+	if route := atlasMap.Spec.Route; route != nil && route.TLS != nil {
+		return route.TLS.Termination == routev1.TLSTerminationReencrypt || route.TLS.Termination == routev1.TLSTerminationPassthrough
+	}
+	return false
+}
+
+func (action *baseAction) kindOf(resource client.Object) string {
+	if kind := resource.GetObjectKind().GroupVersionKind().Kind; len(kind) > 0 {
+		return kind
+	}
+	if gvk, err := apiutil.GVKForObject(resource, action.scheme); err == nil {
+		return gvk.Kind
+	}
+	return action.name
+}
+
+// removeResource deletes a resource of the AtlasMap that is no longer desired. Resources whose API is not
+// served by the cluster are considered removed, and resources that the AtlasMap does not control are left alone.
+func (action *baseAction) removeResource(ctx context.Context, atlasMap *v1alpha1.AtlasMap, resource client.Object) error {
+	if err := action.client.Get(ctx, client.ObjectKeyFromObject(resource), resource); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if !v1.IsControlledBy(resource, atlasMap) {
+		return nil
+	}
+	if err := action.client.Delete(ctx, resource); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	action.recorder.Eventf(atlasMap, corev1.EventTypeNormal, eventReasonDeleted, "Deleted %s %s", action.kindOf(resource), resource.GetName())
+	return nil
 }
 
-func (action *baseAction) updatePhase(ctx context.Context, atlasMap *v1alpha1.AtlasMap, phase v1alpha1.AtlasMapPhase) {
+// controlsResource reports whether the AtlasMap controls an existing resource. Resources with the name the operator
+// uses that were created by someone else are left alone, and a warning event is recorded on the AtlasMap
+func (action *baseAction) controlsResource(atlasMap *v1alpha1.AtlasMap, resource client.Object) bool {
+	if v1.IsControlledBy(resource, atlasMap) {
+		return true
+	}
+	action.log.Info("Skipping resource not controlled by the AtlasMap", "kind", action.kindOf(resource), "name", resource.GetName())
+	action.recorder.Eventf(atlasMap, corev1.EventTypeWarning, eventReasonResourceConflict, "%s %s exists and is not controlled by the AtlasMap", action.kindOf(resource), resource.GetName())
+	return false
+}
+
+// setPhase changes the phase in the AtlasMap status, which the controller writes once all the actions ran
+func (action *baseAction) setPhase(atlasMap *v1alpha1.AtlasMap, phase v1alpha1.AtlasMapPhase) {
 	if atlasMap.Status.Phase != phase {
 		action.log.Info("AtlasMap phase change", "from", atlasMap.Status.Phase, "to", phase)
+		action.recorder.Eventf(atlasMap, corev1.EventTypeNormal, eventReasonPhaseChanged, "Phase changed from %q to %q", atlasMap.Status.Phase, phase)
 		atlasMap.Status.Phase = phase
-		if err := action.client.Status().Update(ctx, atlasMap); err != nil {
-			action.log.Error(err, "Error updating AtlasMap status", "phase", phase)
-		}
 	}
 }
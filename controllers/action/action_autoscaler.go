@@ -0,0 +1,219 @@
+package action
+
+import (
+	"context"
+	"strings"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/go-logr/logr"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const defaultTargetCPUUtilizationPercentage = 80
+
+const (
+	reasonAutoscalerConfigured   = "AutoscalerConfigured"
+	reasonResourceRequestMissing = "ResourceRequestMissing"
+)
+
+// The HorizontalPodAutoscaler is handled as an unstructured object so that it can be served as autoscaling/v2 where
+// the cluster supports it. The autoscaling/v2beta2 types are used to build it as both versions share the same schema.
+type autoscalerAction struct {
+	baseAction
+}
+
+func newAutoscalerAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
+	return &autoscalerAction{
+		newBaseAction(log, mgr, capabilities, "HorizontalPodAutoscaler"),
+	}
+}
+
+func (action *autoscalerAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	hpa := action.newObject()
+	hpa.SetName(atlasMap.Name)
+	hpa.SetNamespace(atlasMap.Namespace)
+
+	if atlasMap.Spec.Autoscaling == nil {
+		atlasMap.Status.Autoscaling = nil
+		meta.RemoveStatusCondition(&atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady)
+		return action.removeResource(ctx, atlasMap, hpa)
+	}
+
+	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, hpa)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if exists && !action.controlsResource(atlasMap, hpa) {
+		atlasMap.Status.Autoscaling = nil
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionAutoscalingReady, v1.ConditionFalse, reasonResourceConflict,
+			"HorizontalPodAutoscaler "+hpa.GetName()+" exists and is not controlled by the AtlasMap")
+		return nil
+	}
+
+	spec := createAtlasMapAutoscalerSpec(atlasMap)
+	desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		hpa.SetName(atlasMap.Name)
+		hpa.SetNamespace(atlasMap.Namespace)
+		hpa.SetLabels(atlasMapLabels(atlasMap))
+		if err := unstructured.SetNestedMap(hpa.Object, desired, "spec"); err != nil {
+			return err
+		}
+		if err := action.deployResource(ctx, atlasMap, hpa); err != nil {
+			return err
+		}
+	} else if err := reconcileAutoscaler(ctx, atlasMap, hpa, desired, action); err != nil {
+		return err
+	}
+
+	status := autoscalingv2beta2.HorizontalPodAutoscalerStatus{}
+	if current, found, err := unstructured.NestedMap(hpa.Object, "status"); err != nil {
+		return err
+	} else if found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(current, &status); err != nil {
+			return err
+		}
+	}
+
+	atlasMap.Status.Autoscaling = &v1alpha1.AtlasMapAutoscalingStatus{
+		MinReplicas:     *spec.MinReplicas,
+		MaxReplicas:     spec.MaxReplicas,
+		CurrentReplicas: status.CurrentReplicas,
+		DesiredReplicas: status.DesiredReplicas,
+	}
+
+	// The autoscaler computes the utilization against the resource requests of the AtlasMap pods
+	if missing := missingResourceRequests(atlasMap, spec); len(missing) > 0 {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionAutoscalingReady, v1.ConditionFalse, reasonResourceRequestMissing,
+			"Utilization targets require "+strings.Join(missing, " and ")+" to be set")
+	} else {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionAutoscalingReady, v1.ConditionTrue, reasonAutoscalerConfigured, "HorizontalPodAutoscaler configured")
+	}
+
+	return nil
+}
+
+// missingResourceRequests returns the AtlasMap fields of the resource requests that the utilization metrics depend on and are not set
+func missingResourceRequests(atlasMap *v1alpha1.AtlasMap, spec *autoscalingv2beta2.HorizontalPodAutoscalerSpec) []string {
+	var missing []string
+	for _, metric := range spec.Metrics {
+		if metric.Resource == nil {
+			continue
+		}
+		if metric.Resource.Name == corev1.ResourceCPU && len(atlasMap.Spec.RequestCPU) == 0 {
+			missing = append(missing, "requestCPU")
+		}
+		if metric.Resource.Name == corev1.ResourceMemory && len(atlasMap.Spec.RequestMemory) == 0 {
+			missing = append(missing, "requestMemory")
+		}
+	}
+	return missing
+}
+
+func (action *autoscalerAction) newObject() *unstructured.Unstructured {
+	hpa := &unstructured.Unstructured{}
+	hpa.SetAPIVersion(action.capabilities.Get().AutoscalerAPIVersion)
+	hpa.SetKind("HorizontalPodAutoscaler")
+	return hpa
+}
+
+func reconcileAutoscaler(ctx context.Context, atlasMap *v1alpha1.AtlasMap, hpa *unstructured.Unstructured, desired map[string]interface{}, action *autoscalerAction) error {
+	current := autoscalingv2beta2.HorizontalPodAutoscalerSpec{}
+	if spec, found, err := unstructured.NestedMap(hpa.Object, "spec"); err != nil {
+		return err
+	} else if found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &current); err != nil {
+			return err
+		}
+	}
+
+	expected := autoscalingv2beta2.HorizontalPodAutoscalerSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(desired, &expected); err != nil {
+		return err
+	}
+
+	// Only compare the fields owned by the operator, the API server defaults the scaling behavior
+	if equality.Semantic.DeepEqual(current.ScaleTargetRef, expected.ScaleTargetRef) &&
+		equality.Semantic.DeepEqual(current.MinReplicas, expected.MinReplicas) &&
+		current.MaxReplicas == expected.MaxReplicas &&
+		equality.Semantic.DeepEqual(current.Metrics, expected.Metrics) {
+		return nil
+	}
+
+	current.ScaleTargetRef = expected.ScaleTargetRef
+	current.MinReplicas = expected.MinReplicas
+	current.MaxReplicas = expected.MaxReplicas
+	current.Metrics = expected.Metrics
+
+	spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&current)
+	if err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedMap(hpa.Object, spec, "spec"); err != nil {
+		return err
+	}
+	return action.updateResource(ctx, atlasMap, hpa)
+}
+
+func createAtlasMapAutoscalerSpec(atlasMap *v1alpha1.AtlasMap) *autoscalingv2beta2.HorizontalPodAutoscalerSpec {
+	autoscaling := atlasMap.Spec.Autoscaling
+
+	minReplicas := int32(1)
+	if autoscaling.MinReplicas != nil {
+		minReplicas = *autoscaling.MinReplicas
+	}
+
+	cpu := autoscaling.TargetCPUUtilizationPercentage
+	if cpu == nil && autoscaling.TargetMemoryUtilizationPercentage == nil {
+		defaultCPU := int32(defaultTargetCPUUtilizationPercentage)
+		cpu = &defaultCPU
+	}
+
+	var metrics []autoscalingv2beta2.MetricSpec
+	if cpu != nil {
+		metrics = append(metrics, utilizationMetric(corev1.ResourceCPU, *cpu))
+	}
+	if memory := autoscaling.TargetMemoryUtilizationPercentage; memory != nil {
+		metrics = append(metrics, utilizationMetric(corev1.ResourceMemory, *memory))
+	}
+
+	return &autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       atlasMap.Name,
+		},
+		MinReplicas: &minReplicas,
+		MaxReplicas: autoscaling.MaxReplicas,
+		Metrics:     metrics,
+	}
+}
+
+func utilizationMetric(name corev1.ResourceName, utilization int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
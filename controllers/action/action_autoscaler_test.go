@@ -0,0 +1,88 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutoscaler(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.RequestCPU = "500m"
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 5}
+	action := &autoscalerAction{newTestBaseAction(t, capabilities.Capabilities{AutoscalerAPIVersion: "autoscaling/v2beta2"})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, hpa))
+	assert.Equal(t, autoscalingv2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: atlasMap.Name}, hpa.Spec.ScaleTargetRef)
+	assert.Equal(t, int32(1), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(5), hpa.Spec.MaxReplicas)
+	assert.Equal(t, &v1alpha1.AtlasMapAutoscalingStatus{MinReplicas: 1, MaxReplicas: 5}, atlasMap.Status.Autoscaling)
+	assert.True(t, meta.IsStatusConditionTrue(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady))
+
+	// Changes made directly to the autoscaler are reverted
+	hpa.Spec.MaxReplicas = 10
+	assert.NoError(t, action.client.Update(context.TODO(), hpa))
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.True(t, exists(t, action.client, hpa))
+	assert.Equal(t, int32(5), hpa.Spec.MaxReplicas)
+
+	atlasMap.Spec.Autoscaling = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.False(t, exists(t, action.client, hpa))
+	assert.Nil(t, atlasMap.Status.Autoscaling)
+	assert.Nil(t, meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady))
+}
+
+func TestAutoscalerResourceRequests(t *testing.T) {
+	memory := int32(70)
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 5}
+	action := &autoscalerAction{newTestBaseAction(t, capabilities.Capabilities{AutoscalerAPIVersion: "autoscaling/v2beta2"})}
+
+	// The default target is the CPU utilization
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonResourceRequestMissing, condition.Reason)
+	assert.Equal(t, "Utilization targets require requestCPU to be set", condition.Message)
+
+	atlasMap.Spec.Autoscaling.TargetMemoryUtilizationPercentage = &memory
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	condition = meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady)
+	assert.Equal(t, "Utilization targets require requestMemory to be set", condition.Message)
+
+	atlasMap.Spec.RequestMemory = "1Gi"
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.True(t, meta.IsStatusConditionTrue(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady))
+}
+
+func TestAutoscalerNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.RequestCPU = "500m"
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 5}
+	action := &autoscalerAction{newTestBaseAction(t, capabilities.Capabilities{AutoscalerAPIVersion: "autoscaling/v2beta2"}, &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace},
+		Spec:       autoscalingv2beta2.HorizontalPodAutoscalerSpec{MaxReplicas: 10},
+	})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, hpa))
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAutoscalingReady)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonResourceConflict, condition.Reason)
+
+	// The autoscaler is not removed with the autoscaling either
+	atlasMap.Spec.Autoscaling = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.True(t, exists(t, action.client, hpa))
+}
@@ -0,0 +1,159 @@
+package action
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const reasonApplicationPropertiesConfigured = "ApplicationPropertiesConfigured"
+
+// applicationPropertiesKey is the file, in the mounted ConfigMap, that Spring Boot loads the application properties from
+const applicationPropertiesKey = "application.properties"
+
+type configMapAction struct {
+	baseAction
+}
+
+func newConfigMapAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
+	return &configMapAction{
+		newBaseAction(log, mgr, capabilities, "ConfigMap"),
+	}
+}
+
+func (action *configMapAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	if len(atlasMap.Spec.ApplicationProperties) == 0 {
+		meta.RemoveStatusCondition(&atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionConfigReady)
+		return action.removeResource(ctx, atlasMap, &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: atlasMapConfigMapName(atlasMap), Namespace: atlasMap.Namespace}})
+	}
+
+	configMap := &corev1.ConfigMap{}
+	desired := createAtlasMapConfigMap(atlasMap)
+
+	err := action.client.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, configMap)
+	if err != nil && errors.IsNotFound(err) {
+		if err := action.deployResource(ctx, atlasMap, desired); err != nil {
+			return err
+		}
+		setApplicationPropertiesConfigured(atlasMap)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// A ConfigMap with the same name created by the user, e.g. to be used in envFrom, is never taken over,
+	// and the pods are not pointed at it
+	if !action.controlsResource(atlasMap, configMap) {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionConfigReady, v1.ConditionFalse, reasonResourceConflict,
+			"ConfigMap "+configMap.Name+" exists and is not controlled by the AtlasMap")
+		return nil
+	}
+
+	updateConfigMap := mergeLabels(configMap, desired.Labels)
+
+	if !equality.Semantic.DeepEqual(configMap.Data, desired.Data) || len(configMap.BinaryData) > 0 {
+		configMap.Data = desired.Data
+		configMap.BinaryData = nil
+		updateConfigMap = true
+	}
+
+	if updateConfigMap {
+		if err := action.updateResource(ctx, atlasMap, configMap); err != nil {
+			return err
+		}
+	}
+	setApplicationPropertiesConfigured(atlasMap)
+	return nil
+}
+
+func setApplicationPropertiesConfigured(atlasMap *v1alpha1.AtlasMap) {
+	SetCondition(atlasMap, v1alpha1.AtlasMapConditionConfigReady, v1.ConditionTrue, reasonApplicationPropertiesConfigured,
+		"ConfigMap "+atlasMapConfigMapName(atlasMap)+" holds the application properties")
+}
+
+// mountsApplicationProperties tells whether the pods load the application properties, which requires their ConfigMap
+// to be controlled by the AtlasMap, as reported by the ConfigReady condition
+func mountsApplicationProperties(atlasMap *v1alpha1.AtlasMap) bool {
+	return len(atlasMap.Spec.ApplicationProperties) > 0 && meta.IsStatusConditionTrue(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionConfigReady)
+}
+
+// atlasMapConfigMapName is the name of the ConfigMap holding the application properties of the AtlasMap
+func atlasMapConfigMapName(atlasMap *v1alpha1.AtlasMap) string {
+	return atlasMap.Name + "-config"
+}
+
+func createAtlasMapConfigMap(atlasMap *v1alpha1.AtlasMap) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: v1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      atlasMapConfigMapName(atlasMap),
+			Namespace: atlasMap.Namespace,
+			Labels:    atlasMapLabels(atlasMap),
+		},
+		Data: map[string]string{
+			applicationPropertiesKey: renderProperties(atlasMap.Spec.ApplicationProperties),
+		},
+	}
+}
+
+// renderProperties writes the properties in the Java properties format, sorted by key so that the content is stable
+func renderProperties(properties map[string]string) string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		builder.WriteString(escapeProperty(key, true))
+		builder.WriteString("=")
+		builder.WriteString(escapeProperty(properties[key], false))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func escapeProperty(value string, key bool) string {
+	var builder strings.Builder
+	for i, r := range value {
+		switch r {
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '=', ':', '#', '!':
+			if key || i == 0 {
+				builder.WriteRune('\\')
+			}
+			builder.WriteRune(r)
+		case ' ':
+			// Leading white space of values and any white space of keys would be dropped or end the key
+			if key || i == 0 {
+				builder.WriteRune('\\')
+			}
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,105 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestConfigMap(t *testing.T) {
+	userData := map[string]string{"LOG_LEVEL": "DEBUG"}
+
+	tests := []struct {
+		name       string
+		properties map[string]string
+		existing   map[string]string
+		controlled bool
+		exists     bool
+		data       map[string]string
+	}{
+		{name: "creates the ConfigMap", properties: map[string]string{"b": "2", "a": "1"}, exists: true,
+			data: map[string]string{applicationPropertiesKey: "a=1\nb=2\n"}},
+		{name: "reverts changes", properties: map[string]string{"a": "1"}, existing: map[string]string{applicationPropertiesKey: "a=2\n"}, controlled: true, exists: true,
+			data: map[string]string{applicationPropertiesKey: "a=1\n"}},
+		{name: "removes the controlled ConfigMap", existing: map[string]string{applicationPropertiesKey: "a=1\n"}, controlled: true, exists: false},
+		{name: "keeps an uncontrolled ConfigMap", existing: userData, exists: true, data: userData},
+		{name: "does not take over an uncontrolled ConfigMap", properties: map[string]string{"a": "1"}, existing: userData, exists: true, data: userData},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atlasMap := newTestAtlasMap()
+			atlasMap.Spec.ApplicationProperties = test.properties
+
+			var objects []client.Object
+			if test.existing != nil {
+				var configMap client.Object = &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: atlasMapConfigMapName(atlasMap), Namespace: atlasMap.Namespace}, Data: test.existing}
+				if test.controlled {
+					configMap = controlledBy(t, atlasMap, configMap)
+				}
+				objects = append(objects, configMap)
+			}
+			action := &configMapAction{newTestBaseAction(t, capabilities.Capabilities{}, objects...)}
+			assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+			configMap := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: atlasMapConfigMapName(atlasMap), Namespace: atlasMap.Namespace}}
+			assert.Equal(t, test.exists, exists(t, action.client, configMap))
+			if test.exists {
+				assert.Equal(t, test.data, configMap.Data)
+			}
+		})
+	}
+}
+
+func TestRenderProperties(t *testing.T) {
+	assert.Equal(t, "a\\=b=\\ c\\\\d\nkey=value\\nnext\n", renderProperties(map[string]string{"a=b": " c\\d", "key": "value\nnext"}))
+}
+
+func TestApplicationPropertiesMount(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.ApplicationProperties = map[string]string{"a": "1"}
+	userConfigMap := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: atlasMapConfigMapName(atlasMap), Namespace: atlasMap.Namespace}, Data: map[string]string{"LOG_LEVEL": "DEBUG"}}
+	base := newTestBaseAction(t, capabilities.Capabilities{}, userConfigMap)
+	springConfigLocation := corev1.EnvVar{Name: springConfigLocationEnv, Value: "file:" + configMountPath + "/"}
+
+	// The pods are not pointed at a ConfigMap that the AtlasMap does not control
+	for _, action := range []Action{&configMapAction{base}, &deploymentAction{base}} {
+		assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	}
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionConfigReady)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, v1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonResourceConflict, condition.Reason)
+	}
+	podSpec := getDeployment(t, base.client, atlasMap).Spec.Template.Spec
+	assert.Empty(t, podSpec.Volumes)
+	assert.NotContains(t, podSpec.Containers[0].Env, springConfigLocation)
+
+	// Once the user ConfigMap is gone, the AtlasMap creates its own and mounts it
+	assert.NoError(t, base.client.Delete(context.TODO(), userConfigMap))
+	for _, action := range []Action{&configMapAction{base}, &deploymentAction{base}} {
+		assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	}
+	assert.True(t, meta.IsStatusConditionTrue(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionConfigReady))
+	podSpec = getDeployment(t, base.client, atlasMap).Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, corev1.Volume{Name: configVolume, VolumeSource: corev1.VolumeSource{
+		ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: atlasMapConfigMapName(atlasMap)}},
+	}})
+	assert.Contains(t, podSpec.Containers[0].Env, springConfigLocation)
+
+	// Removing the properties removes the condition and the mount
+	atlasMap.Spec.ApplicationProperties = nil
+	for _, action := range []Action{&configMapAction{base}, &deploymentAction{base}} {
+		assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	}
+	assert.Nil(t, meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionConfigReady))
+	podSpec = getDeployment(t, base.client, atlasMap).Spec.Template.Spec
+	assert.Empty(t, podSpec.Volumes)
+	assert.NotContains(t, podSpec.Containers[0].Env, springConfigLocation)
+}
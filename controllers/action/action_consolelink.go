@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
 	"github.com/atlasmap/atlasmap-operator/controllers/util"
 	"github.com/go-logr/logr"
 	consolev1 "github.com/openshift/api/console/v1"
 	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,42 +22,51 @@ type consoleLinkAction struct {
 	baseAction
 }
 
-func newConsoleLinkAction(log logr.Logger, mgr manager.Manager) Action {
+func newConsoleLinkAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
 	return &consoleLinkAction{
-		newBaseAction(log, mgr, "ConsoleLink"),
+		newBaseAction(log, mgr, capabilities, "ConsoleLink"),
 	}
 }
 
 func (action *consoleLinkAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
-	isOpenShift, err := util.IsOpenShift(action.config)
+	if err := action.handleConsoleLink(ctx, atlasMap); err != nil {
+		action.recorder.Eventf(atlasMap, corev1.EventTypeWarning, eventReasonConsoleLinkFailed, "Failed to reconcile ConsoleLink %s: %v", util.ConsoleLinkName(atlasMap), err)
+		return err
+	}
+	return nil
+}
+
+func (action *consoleLinkAction) handleConsoleLink(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	if !action.capabilities.Get().ConsoleLinks {
+		return nil
+	}
+
+	// The ConsoleLink points to the Route
+	if action.exposureType(atlasMap) != v1alpha1.AtlasMapExposureRoute {
+		return RemoveConsoleLink(ctx, action.client, atlasMap)
+	}
+
+	route, err := action.getAtlasMapRoute(ctx, atlasMap)
 	if err != nil {
 		return err
 	}
 
-	if isOpenShift {
-		route, err := action.getAtlasMapRoute(ctx, atlasMap)
-		if err != nil {
+	consoleLinkName := util.ConsoleLinkName(atlasMap)
+	consoleLink := &consolev1.ConsoleLink{}
+	err = action.client.Get(ctx, types.NamespacedName{Name: consoleLinkName}, consoleLink)
+	if err != nil && errors.IsNotFound(err) {
+		consoleLink = createNamespaceDashboardLink(consoleLinkName, route, atlasMap)
+		if err := action.client.Create(ctx, consoleLink); err != nil {
 			return err
 		}
-
-		consoleLinkName := util.ConsoleLinkName(atlasMap)
-		consoleLink := &consolev1.ConsoleLink{}
-		err = action.client.Get(ctx, types.NamespacedName{Name: consoleLinkName}, consoleLink)
-		if err != nil && errors.IsNotFound(err) {
-			consoleLink = createNamespaceDashboardLink(consoleLinkName, route, atlasMap)
-			if err := action.client.Create(ctx, consoleLink); err != nil {
-				return err
-			}
-		} else if err == nil && consoleLink != nil {
-			if atlasMap.DeletionTimestamp != nil {
-				if err := action.client.Delete(ctx, consoleLink); err != nil {
-					action.log.Error(err, "Error deleting console link.")
-				}
-			}
-
-			if err := reconcileConsoleLink(ctx, atlasMap, route, consoleLink, action.client); err != nil {
-				return err
-			}
+		action.recorder.Eventf(atlasMap, corev1.EventTypeNormal, eventReasonCreated, "Created ConsoleLink %s", consoleLinkName)
+	} else if err == nil && consoleLink != nil {
+		if !isConsoleLinkOf(consoleLink, atlasMap) && len(consoleLink.Labels[util.ConsoleLinkNameLabel]) > 0 {
+			action.recorder.Eventf(atlasMap, corev1.EventTypeWarning, eventReasonResourceConflict, "ConsoleLink %s exists and belongs to another AtlasMap", consoleLinkName)
+			return nil
+		}
+		if err := reconcileConsoleLink(ctx, atlasMap, route, consoleLink, action.client); err != nil {
+			return err
 		}
 	}
 
@@ -62,7 +74,8 @@ func (action *consoleLinkAction) Handle(ctx context.Context, atlasMap *v1alpha1.
 }
 
 func reconcileConsoleLink(ctx context.Context, atlasMap *v1alpha1.AtlasMap, route *routev1.Route, link *consolev1.ConsoleLink, client client.Client) error {
-	updateConsoleLink := false
+	// Label ConsoleLinks created by earlier operator versions so that they are garbage collected
+	updateConsoleLink := mergeLabels(link, consoleLinkLabels(atlasMap))
 	url := "https://" + route.Spec.Host
 	if link.Spec.Href != url {
 		link.Spec.Href = url
@@ -88,7 +101,7 @@ func createNamespaceDashboardLink(name string, route *routev1.Route, atlasMap *v
 	return &consolev1.ConsoleLink{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   name,
-			Labels: atlasMapLabels(atlasMap),
+			Labels: consoleLinkLabels(atlasMap),
 		},
 		Spec: consolev1.ConsoleLinkSpec{
 			Link: consolev1.Link{
@@ -115,18 +128,32 @@ func (action *consoleLinkAction) getAtlasMapRoute(ctx context.Context, atlasMap
 	return route, err
 }
 
-func (action *consoleLinkAction) RemoveConsoleLink(atlasMap *v1alpha1.AtlasMap) error {
-	consoleLinkName := util.ConsoleLinkName(atlasMap)
+// RemoveConsoleLink deletes the cluster-scoped ConsoleLink of the AtlasMap, if any. A ConsoleLink of the same
+// name that is not labelled with the name and namespace of the AtlasMap is left alone.
+func RemoveConsoleLink(ctx context.Context, c client.Client, atlasMap *v1alpha1.AtlasMap) error {
 	consoleLink := &consolev1.ConsoleLink{}
-	err := action.client.Get(context.TODO(), types.NamespacedName{Name: consoleLinkName}, consoleLink)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return err
-		}
-	} else {
-		if err := action.client.Delete(context.TODO(), consoleLink); err != nil {
-			return err
+	if err := c.Get(ctx, types.NamespacedName{Name: util.ConsoleLinkName(atlasMap)}, consoleLink); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
 		}
+		return err
+	}
+	if !isConsoleLinkOf(consoleLink, atlasMap) {
+		return nil
+	}
+	if err := c.Delete(ctx, consoleLink); err != nil && !errors.IsNotFound(err) {
+		return err
 	}
 	return nil
 }
+
+// isConsoleLinkOf tells whether the ConsoleLink is labelled with the name and namespace of the AtlasMap
+func isConsoleLinkOf(link *consolev1.ConsoleLink, atlasMap *v1alpha1.AtlasMap) bool {
+	return link.Labels[util.ConsoleLinkNameLabel] == atlasMap.Name && link.Labels[util.ConsoleLinkNamespaceLabel] == atlasMap.Namespace
+}
+
+func consoleLinkLabels(atlasMap *v1alpha1.AtlasMap) map[string]string {
+	labels := atlasMapLabels(atlasMap)
+	labels[util.ConsoleLinkNamespaceLabel] = atlasMap.Namespace
+	return labels
+}
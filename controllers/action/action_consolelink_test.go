@@ -0,0 +1,35 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	consolev1 "github.com/openshift/api/console/v1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRemoveConsoleLink(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	scheme := newTestScheme(t)
+	assert.NoError(t, consolev1.AddToScheme(scheme))
+
+	other := newTestAtlasMap()
+	other.Namespace = "other"
+	link := &consolev1.ConsoleLink{ObjectMeta: v1.ObjectMeta{Name: util.ConsoleLinkName(atlasMap), Labels: consoleLinkLabels(other)}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(link).Build()
+
+	// A ConsoleLink of the same name that belongs to another AtlasMap is kept
+	assert.NoError(t, RemoveConsoleLink(context.TODO(), c, atlasMap))
+	assert.True(t, exists(t, c, link))
+
+	link.Labels = consoleLinkLabels(atlasMap)
+	assert.NoError(t, c.Update(context.TODO(), link))
+	assert.NoError(t, RemoveConsoleLink(context.TODO(), c, atlasMap))
+	assert.False(t, exists(t, c, link))
+
+	// Nothing to remove
+	assert.NoError(t, RemoveConsoleLink(context.TODO(), c, atlasMap))
+}
@@ -2,14 +2,18 @@ package action
 
 import (
 	"context"
+	"strings"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/catalog"
 	"github.com/atlasmap/atlasmap-operator/controllers/resources"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -18,7 +22,7 @@ import (
 )
 
 const (
-	atlasMapVersionAnnotation    = "atlasmap.io/atlasmap.resource.version"
+	javaOptionsEnv               = "JAVA_OPTIONS"
 	livenessInitialDelaySeconds  = 60
 	portAtlasMap                 = 8585
 	portJolokia                  = 8778
@@ -27,27 +31,67 @@ const (
 	readinessFailureThreshold    = 5
 )
 
+// Spring Boot settings serving AtlasMap over HTTPS with the OpenShift service serving certificate
+const (
+	sslEnabledEnv     = "SERVER_SSL_ENABLED"
+	sslCertificateEnv = "SERVER_SSL_CERTIFICATE"
+	sslPrivateKeyEnv  = "SERVER_SSL_CERTIFICATEPRIVATEKEY"
+	tlsVolume         = "tls"
+	tlsMountPath      = "/etc/atlasmap/tls"
+)
+
+// The AtlasMap workspace, holding mapping definitions and uploaded libraries, is stored on the data volume when storage is configured
+const (
+	dataVolume          = "data"
+	workspaceMountPath  = "/var/lib/atlasmap"
+	workspaceJavaOption = "-Datlasmap.workspace=" + workspaceMountPath
+	// atlasMapFSGroup is the group of the user the AtlasMap image runs as
+	atlasMapFSGroup = 185
+)
+
+// The application properties of the AtlasMap are mounted into a directory that Spring Boot additionally loads its configuration from
+const (
+	springConfigLocationEnv = "SPRING_CONFIG_ADDITIONAL_LOCATION"
+	configVolume            = "config"
+	configMountPath         = "/etc/atlasmap/config"
+)
+
+// legacyResourceVersionAnnotation was used by earlier operator versions to decide whether the AtlasMap or the Deployment
+// owned the replica count
+const legacyResourceVersionAnnotation = "atlasmap.io/atlasmap.resource.version"
+
 type deploymentAction struct {
 	baseAction
 }
 
-func newDeploymentAction(log logr.Logger, mgr manager.Manager) Action {
+func newDeploymentAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
 	return &deploymentAction{
-		newBaseAction(log, mgr, "Deployment"),
+		newBaseAction(log, mgr, capabilities, "Deployment"),
 	}
 }
 
 func (action *deploymentAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	entry, err := atlasMapCatalogEntry(atlasMap)
+	if !setVersionCondition(atlasMap, entry, err) {
+		// Keep any existing deployment running until a supported version is requested
+		action.log.Info("Skipping unsupported AtlasMap version", "version", atlasMapVersion(atlasMap), "reason", err.Error())
+		action.recorder.Event(atlasMap, corev1.EventTypeWarning, eventReasonUnsupportedVersion, err.Error())
+		return nil
+	}
+
 	deployment, err := getAtlasMapDeployment(ctx, action, atlasMap)
 
 	if err != nil && errors.IsNotFound(err) {
-		probePath, err := atlasMapProbePath(atlasMap)
-		if err != nil {
+		deployment = createAtlasMapDeployment(atlasMap, entry)
+		configurePodTLS(&deployment.Spec.Template.Spec, atlasMap, action.servesTLS(atlasMap))
+		configurePodScheduling(&deployment.Spec.Template.Spec, atlasMap)
+		configureApplicationProperties(&deployment.Spec.Template.Spec, atlasMap)
+		configureStorage(deployment, atlasMap, !action.capabilities.Get().Routes)
+		configureContainerEnv(&deployment.Spec.Template.Spec.Containers[0], atlasMap, entry, action.servesTLS(atlasMap))
+		if _, err := reconcileConfigHash(ctx, &deployment.Spec.Template, atlasMap, action); err != nil {
 			return err
 		}
 
-		deployment = createAtlasMapDeployment(atlasMap, probePath)
-
 		if err := resources.ConfigureResources(atlasMap, &deployment.Spec.Template.Spec.Containers[0]); err != nil {
 			return err
 		}
@@ -55,9 +99,27 @@ func (action *deploymentAction) Handle(ctx context.Context, atlasMap *v1alpha1.A
 		if err := action.deployResource(ctx, atlasMap, deployment); err != nil {
 			return err
 		}
+
+		setDeploymentConditions(atlasMap, deployment)
 	} else if err == nil && deployment != nil {
+		if !action.controlsResource(atlasMap, deployment) {
+			SetCondition(atlasMap, v1alpha1.AtlasMapConditionAvailable, v1.ConditionFalse, reasonResourceConflict, "Deployment "+deployment.Name+" exists and is not controlled by the AtlasMap")
+			return nil
+		}
+
 		deployment = deployment.DeepCopy()
 
+		// Refresh the labels of Deployments created by earlier operator versions
+		if err := reconcileMetadata(ctx, deployment, atlasMap, action); err != nil {
+			return err
+		}
+
+		// The Deployment selector is immutable, so a Deployment whose selector includes labels that
+		// changed since it was created, like the AtlasMap and operator versions, has to be replaced
+		if !equality.Semantic.DeepEqual(deployment.Spec.Selector, &v1.LabelSelector{MatchLabels: atlasMapSelectorLabels(atlasMap)}) {
+			return replaceDeployment(ctx, deployment, atlasMap, action)
+		}
+
 		// Reconcile replicas
 		if err := reconcileReplicas(ctx, deployment, atlasMap, action); err != nil {
 			return err
@@ -65,19 +127,54 @@ func (action *deploymentAction) Handle(ctx context.Context, atlasMap *v1alpha1.A
 
 		containers := deployment.Spec.Template.Spec.Containers
 		if len(containers) > 0 {
-			// Reconcile AtlasMap image
-			if err := reconcileImage(ctx, deployment, atlasMap, action.client); err != nil {
+			// Reconcile AtlasMap image and version specific settings
+			if err := reconcileImage(ctx, deployment, atlasMap, entry, action); err != nil {
+				return err
+			}
+
+			// Reconcile HTTPS on the AtlasMap port
+			if configurePodTLS(&deployment.Spec.Template.Spec, atlasMap, action.servesTLS(atlasMap)) {
+				if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
+					return err
+				}
+			}
+
+			// Reconcile the configuration passed to the AtlasMap process
+			propertiesChanged := configureApplicationProperties(&deployment.Spec.Template.Spec, atlasMap)
+			envChanged := configureContainerEnv(&deployment.Spec.Template.Spec.Containers[0], atlasMap, entry, action.servesTLS(atlasMap))
+			// Roll the AtlasMap pods when the content of the ConfigMaps and Secrets they use changes
+			hashChanged, err := reconcileConfigHash(ctx, &deployment.Spec.Template, atlasMap, action)
+			if err != nil {
 				return err
 			}
+			if envChanged || propertiesChanged || hashChanged {
+				if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
+					return err
+				}
+			}
+
+			// Reconcile the volume persisting the AtlasMap workspace
+			if configureStorage(deployment, atlasMap, !action.capabilities.Get().Routes) {
+				if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
+					return err
+				}
+			}
+
+			// Reconcile where the AtlasMap pods are scheduled
+			if configurePodScheduling(&deployment.Spec.Template.Spec, atlasMap) {
+				if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
+					return err
+				}
+			}
 
 			// Reconcile resources
-			if err := reconcileResources(ctx, deployment, atlasMap, action.client); err != nil {
+			if err := reconcileResources(ctx, deployment, atlasMap, action); err != nil {
 				return err
 			}
 		}
 
-		// Update resource version
-		if err := updateResourceVersion(ctx, deployment, atlasMap, action.client); err != nil {
+		// Remove the replica synchronization annotation used by earlier operator versions
+		if err := removeLegacyAnnotation(ctx, deployment, atlasMap, action); err != nil {
 			return err
 		}
 	} else {
@@ -94,52 +191,40 @@ func getAtlasMapDeployment(ctx context.Context, action *deploymentAction, atlasM
 	return deployment, err
 }
 
-func createAtlasMapDeployment(atlasMap *v1alpha1.AtlasMap, probePath string) *appsv1.Deployment {
-	return &appsv1.Deployment{
+func createAtlasMapDeployment(atlasMap *v1alpha1.AtlasMap, entry *catalog.Entry) *appsv1.Deployment {
+	image := atlasMapImage(atlasMap)
+	deployment := &appsv1.Deployment{
 		TypeMeta: v1.TypeMeta{
 			APIVersion: "apps/v1",
 			Kind:       "Deployment",
 		},
 		ObjectMeta: v1.ObjectMeta{
-			Name:        atlasMap.Name,
-			Namespace:   atlasMap.Namespace,
-			Labels:      atlasMapLabels(atlasMap),
-			Annotations: map[string]string{atlasMapVersionAnnotation: atlasMap.GetResourceVersion()},
+			Name:      atlasMap.Name,
+			Namespace: atlasMap.Namespace,
+			Labels:    atlasMapLabels(atlasMap),
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: &atlasMap.Spec.Replicas,
+			Replicas: atlasMapReplicas(atlasMap),
 			Selector: &v1.LabelSelector{
-				MatchLabels: atlasMapLabels(atlasMap),
+				MatchLabels: atlasMapSelectorLabels(atlasMap),
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: v1.ObjectMeta{
-					Labels: atlasMapLabels(atlasMap),
+					Labels: atlasMapPodLabels(atlasMap),
 				},
 				Spec: corev1.PodSpec{
+					ImagePullSecrets: atlasMap.Spec.ImagePullSecrets,
 					Containers: []corev1.Container{{
-						Image:           atlasMapImage(atlasMap),
-						ImagePullPolicy: corev1.PullAlways,
+						Image:           image,
+						ImagePullPolicy: atlasMapImagePullPolicy(atlasMap, image),
 						Name:            "atlasmap",
-						Ports: []corev1.ContainerPort{
-							{
-								ContainerPort: portAtlasMap,
-								Name:          "http",
-							},
-							{
-								ContainerPort: portJolokia,
-								Name:          "jolokia",
-							},
-							{
-								ContainerPort: portPrometheus,
-								Name:          "prometheus",
-							},
-						},
+						Ports:           atlasMapContainerPorts(entry),
 						LivenessProbe: &corev1.Probe{
 							Handler: corev1.Handler{
 								HTTPGet: &corev1.HTTPGetAction{
 									Scheme: corev1.URISchemeHTTP,
 									Port:   intstr.FromString("http"),
-									Path:   probePath,
+									Path:   entry.ProbePath,
 								}},
 							InitialDelaySeconds: livenessInitialDelaySeconds,
 						},
@@ -148,7 +233,7 @@ func createAtlasMapDeployment(atlasMap *v1alpha1.AtlasMap, probePath string) *ap
 								HTTPGet: &corev1.HTTPGetAction{
 									Scheme: corev1.URISchemeHTTP,
 									Port:   intstr.FromString("http"),
-									Path:   probePath,
+									Path:   entry.ProbePath,
 								}},
 							InitialDelaySeconds: readinessInitialDelaySeconds,
 							FailureThreshold:    readinessFailureThreshold,
@@ -158,25 +243,331 @@ func createAtlasMapDeployment(atlasMap *v1alpha1.AtlasMap, probePath string) *ap
 			},
 		},
 	}
+	return deployment
 }
 
-func reconcileReplicas(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) error {
-	if annotations := deployment.GetAnnotations(); annotations != nil && annotations[atlasMapVersionAnnotation] == atlasMap.GetResourceVersion() {
-		// Reconcile Deployment.Spec.Replicas replicas to AtlasMap.Spec.Replicas
-		if replicas := deployment.Spec.Replicas; atlasMap.Spec.Replicas != *replicas {
-			atlasMap.Spec.Replicas = *replicas
-			if err := action.client.Update(ctx, atlasMap); err != nil {
-				return err
+func atlasMapContainerPorts(entry *catalog.Entry) []corev1.ContainerPort {
+	return []corev1.ContainerPort{
+		{
+			ContainerPort: entry.Ports.HTTP,
+			Name:          "http",
+			Protocol:      corev1.ProtocolTCP,
+		},
+		{
+			ContainerPort: entry.Ports.Jolokia,
+			Name:          "jolokia",
+			Protocol:      corev1.ProtocolTCP,
+		},
+		{
+			ContainerPort: entry.Ports.Prometheus,
+			Name:          "prometheus",
+			Protocol:      corev1.ProtocolTCP,
+		},
+	}
+}
+
+// configurePodTLS mounts the serving certificate and enables HTTPS on the AtlasMap port, or reverts to HTTP,
+// and reports whether the pod changed
+func configurePodTLS(podSpec *corev1.PodSpec, atlasMap *v1alpha1.AtlasMap, enabled bool) bool {
+	container := &podSpec.Containers[0]
+	var volume *corev1.Volume
+	var mount *corev1.VolumeMount
+	scheme := corev1.URISchemeHTTP
+
+	if enabled {
+		volume = &corev1.Volume{
+			Name: tlsVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: atlasMapServingCertSecretName(atlasMap),
+				},
+			},
+		}
+		mount = &corev1.VolumeMount{
+			Name:      tlsVolume,
+			MountPath: tlsMountPath,
+			ReadOnly:  true,
+		}
+		scheme = corev1.URISchemeHTTPS
+	}
+
+	changed := setVolume(podSpec, tlsVolume, volume)
+	if setVolumeMount(container, tlsVolume, mount) {
+		changed = true
+	}
+	for _, probe := range []*corev1.Probe{container.LivenessProbe, container.ReadinessProbe} {
+		if probe != nil && probe.HTTPGet != nil && probe.HTTPGet.Scheme != scheme {
+			probe.HTTPGet.Scheme = scheme
+			changed = true
+		}
+	}
+	return changed
+}
+
+// configureContainerEnv sets the environment of the AtlasMap container: the version specific and HTTPS settings
+// of the operator, overridden by the variables of the AtlasMap, and the sources of the AtlasMap. It reports whether the container changed
+func configureContainerEnv(container *corev1.Container, atlasMap *v1alpha1.AtlasMap, entry *catalog.Entry, servesTLS bool) bool {
+	desired := &corev1.Container{}
+	javaOptions := entry.JavaOptions
+	if atlasMap.Spec.Storage != nil {
+		javaOptions = strings.TrimSpace(javaOptions + " " + workspaceJavaOption)
+	}
+	setEnvVar(desired, javaOptionsEnv, javaOptions)
+	if servesTLS {
+		setEnvVar(desired, sslEnabledEnv, "true")
+		setEnvVar(desired, sslCertificateEnv, tlsMountPath+"/tls.crt")
+		setEnvVar(desired, sslPrivateKeyEnv, tlsMountPath+"/tls.key")
+	}
+	if mountsApplicationProperties(atlasMap) {
+		setEnvVar(desired, springConfigLocationEnv, "file:"+configMountPath+"/")
+	}
+	for _, env := range atlasMap.Spec.Env {
+		// The workspace must stay on the volume when the Java options are replaced
+		if env.Name == javaOptionsEnv && atlasMap.Spec.Storage != nil && env.ValueFrom == nil && !strings.Contains(env.Value, workspaceJavaOption) {
+			env.Value = strings.TrimSpace(env.Value + " " + workspaceJavaOption)
+		}
+		replaced := false
+		for i := range desired.Env {
+			if desired.Env[i].Name == env.Name {
+				desired.Env[i] = env
+				replaced = true
 			}
 		}
-	} else {
-		// Reconcile AtlasMap.Spec.Replicas to Deployment.Spec.Replicas
-		if replicas := atlasMap.Spec.Replicas; *deployment.Spec.Replicas != replicas {
-			deployment.Annotations[atlasMapVersionAnnotation] = atlasMap.GetResourceVersion()
-			deployment.Spec.Replicas = &replicas
-			if err := action.client.Update(ctx, deployment); err != nil {
+		if !replaced {
+			desired.Env = append(desired.Env, env)
+		}
+	}
+
+	changed := false
+	// Fields defaulted by the API server, like the API version of field references, are not compared
+	if len(container.Env) != len(desired.Env) || !equality.Semantic.DeepDerivative(desired.Env, container.Env) {
+		container.Env = desired.Env
+		changed = true
+	}
+	if len(container.EnvFrom) != len(atlasMap.Spec.EnvFrom) || !equality.Semantic.DeepDerivative(atlasMap.Spec.EnvFrom, container.EnvFrom) {
+		container.EnvFrom = atlasMap.Spec.EnvFrom
+		changed = true
+	}
+	return changed
+}
+
+// reconcileConfigHash stamps the hash of the ConfigMaps and Secrets used by the AtlasMap pods onto the pod template
+// and reports whether it changed
+func reconcileConfigHash(ctx context.Context, template *corev1.PodTemplateSpec, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) (bool, error) {
+	hash, err := configHash(ctx, action.client, atlasMap)
+	if err != nil {
+		return false, err
+	}
+	if template.Annotations[configHashAnnotation] == hash {
+		return false, nil
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[configHashAnnotation] = hash
+	return true, nil
+}
+
+// configureStorage mounts the PersistentVolumeClaim holding the AtlasMap workspace and, unless the volume can be
+// shared by pods on different nodes, replaces pods instead of rolling them. Unless the platform assigns one, as OpenShift
+// does, the pods get a filesystem group so that they can write to the volume. It reports whether the deployment changed
+func configureStorage(deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, setFSGroup bool) bool {
+	podSpec := &deployment.Spec.Template.Spec
+	var volume *corev1.Volume
+	var mount *corev1.VolumeMount
+	var fsGroup *int64
+
+	if atlasMap.Spec.Storage != nil {
+		volume = &corev1.Volume{
+			Name: dataVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: atlasMapClaimName(atlasMap),
+				},
+			},
+		}
+		mount = &corev1.VolumeMount{
+			Name:      dataVolume,
+			MountPath: workspaceMountPath,
+		}
+		if setFSGroup {
+			group := int64(atlasMapFSGroup)
+			fsGroup = &group
+		}
+	}
+
+	changed := setVolume(podSpec, dataVolume, volume)
+	if setVolumeMount(&podSpec.Containers[0], dataVolume, mount) {
+		changed = true
+	}
+
+	if current := podSpec.SecurityContext; fsGroup != nil && (current == nil || current.FSGroup == nil || *current.FSGroup != *fsGroup) {
+		if podSpec.SecurityContext == nil {
+			podSpec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		podSpec.SecurityContext.FSGroup = fsGroup
+		changed = true
+	} else if fsGroup == nil && current != nil && current.FSGroup != nil {
+		current.FSGroup = nil
+		changed = true
+	}
+
+	// A new pod cannot attach an exclusive volume while the pod it replaces still uses it
+	strategy := appsv1.RollingUpdateDeploymentStrategyType
+	if !atlasMapStorageShared(atlasMap) {
+		strategy = appsv1.RecreateDeploymentStrategyType
+	}
+	if deployment.Spec.Strategy.Type != strategy && !(len(deployment.Spec.Strategy.Type) == 0 && strategy == appsv1.RollingUpdateDeploymentStrategyType) {
+		deployment.Spec.Strategy = appsv1.DeploymentStrategy{Type: strategy}
+		changed = true
+	}
+	return changed
+}
+
+// configureApplicationProperties mounts the ConfigMap holding the application properties of the AtlasMap, when there
+// are any and the AtlasMap controls it, and reports whether the pod changed
+func configureApplicationProperties(podSpec *corev1.PodSpec, atlasMap *v1alpha1.AtlasMap) bool {
+	var volume *corev1.Volume
+	var mount *corev1.VolumeMount
+
+	if mountsApplicationProperties(atlasMap) {
+		volume = &corev1.Volume{
+			Name: configVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: atlasMapConfigMapName(atlasMap),
+					},
+				},
+			},
+		}
+		mount = &corev1.VolumeMount{
+			Name:      configVolume,
+			MountPath: configMountPath,
+			ReadOnly:  true,
+		}
+	}
+
+	changed := setVolume(podSpec, configVolume, volume)
+	if setVolumeMount(&podSpec.Containers[0], configVolume, mount) {
+		changed = true
+	}
+	return changed
+}
+
+// configurePodScheduling applies the node selector, tolerations, affinity, topology spread constraints, priority
+// and runtime class of the AtlasMap pod template, and reports whether the pod changed
+func configurePodScheduling(podSpec *corev1.PodSpec, atlasMap *v1alpha1.AtlasMap) bool {
+	template := atlasMap.Spec.PodTemplate
+	if template == nil {
+		template = &v1alpha1.AtlasMapPodTemplateSpec{}
+	}
+	changed := false
+
+	if !equality.Semantic.DeepEqual(podSpec.NodeSelector, template.NodeSelector) {
+		podSpec.NodeSelector = template.NodeSelector
+		changed = true
+	}
+
+	if !equality.Semantic.DeepEqual(podSpec.Tolerations, template.Tolerations) {
+		podSpec.Tolerations = template.Tolerations
+		changed = true
+	}
+
+	affinity := template.Affinity
+	if affinity == nil {
+		affinity = defaultAtlasMapAffinity(atlasMap)
+	}
+	if !equality.Semantic.DeepEqual(podSpec.Affinity, affinity) {
+		podSpec.Affinity = affinity
+		changed = true
+	}
+
+	if !equality.Semantic.DeepEqual(podSpec.TopologySpreadConstraints, template.TopologySpreadConstraints) {
+		podSpec.TopologySpreadConstraints = template.TopologySpreadConstraints
+		changed = true
+	}
+
+	// The cluster may default the priority class, so it is only reconciled when configured
+	if len(template.PriorityClassName) > 0 && podSpec.PriorityClassName != template.PriorityClassName {
+		podSpec.PriorityClassName = template.PriorityClassName
+		changed = true
+	}
+
+	if !equality.Semantic.DeepEqual(podSpec.RuntimeClassName, template.RuntimeClassName) {
+		podSpec.RuntimeClassName = template.RuntimeClassName
+		changed = true
+	}
+
+	return changed
+}
+
+// defaultAtlasMapAffinity prefers running AtlasMap pods on different nodes when more than one replica may run
+func defaultAtlasMapAffinity(atlasMap *v1alpha1.AtlasMap) *corev1.Affinity {
+	if atlasMapMaxReplicas(atlasMap) <= 1 {
+		return nil
+	}
+
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &v1.LabelSelector{
+							MatchLabels: atlasMapSelectorLabels(atlasMap),
+						},
+						TopologyKey: corev1.LabelHostname,
+					},
+				},
+			},
+		},
+	}
+}
+
+func reconcileMetadata(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) error {
+	updateDeployment := false
+	if mergeLabels(deployment, atlasMapLabels(atlasMap)) {
+		updateDeployment = true
+	}
+
+	if mergeLabels(&deployment.Spec.Template, atlasMapPodLabels(atlasMap)) {
+		updateDeployment = true
+	}
+
+	if updateDeployment {
+		return action.updateResource(ctx, atlasMap, deployment)
+	}
+	return nil
+}
+
+func replaceDeployment(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) error {
+	action.log.Info("Replacing Deployment with outdated selector", "Deployment.Namespace", deployment.Namespace, "Deployment.Name", deployment.Name)
+
+	// The Deployment is created again once its deletion is observed
+	if err := action.client.Delete(ctx, deployment, client.PropagationPolicy(v1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	SetCondition(atlasMap, v1alpha1.AtlasMapConditionProgressing, v1.ConditionTrue, reasonReplacingDeployment, "Replacing the AtlasMap Deployment to update its selector")
+	action.setPhase(atlasMap, v1alpha1.AtlasMapPhasePhaseDeploying)
+	return nil
+}
+
+func reconcileReplicas(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) error {
+	// Unless an autoscaler owns the replica count, AtlasMap.Spec.Replicas is the only source of truth
+	// and any drift on the deployment is reverted
+	if atlasMap.Spec.Autoscaling == nil {
+		if replicas := atlasMapReplicas(atlasMap); deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != *replicas {
+			previous := int32(1)
+			if deployment.Spec.Replicas != nil {
+				previous = *deployment.Spec.Replicas
+			}
+			deployment.Spec.Replicas = replicas
+			if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
 				return err
 			}
+			action.recorder.Eventf(atlasMap, corev1.EventTypeNormal, eventReasonReplicasUpdated, "Scaled Deployment %s from %d to %d replicas", deployment.Name, previous, *replicas)
 		}
 	}
 
@@ -186,53 +577,76 @@ func reconcileReplicas(ctx context.Context, deployment *appsv1.Deployment, atlas
 		return err
 	}
 
+	setDeploymentConditions(atlasMap, updatedDeployment)
+
+	atlasMap.Status.Replicas = updatedDeployment.Status.Replicas
+	selector, err := v1.LabelSelectorAsSelector(updatedDeployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	atlasMap.Status.LabelSelector = selector.String()
+
 	if *updatedDeployment.Spec.Replicas == 0 && updatedDeployment.Status.ReadyReplicas == 0 {
-		action.updatePhase(ctx, atlasMap, v1alpha1.AtlasMapPhasePhaseUndeployed)
+		action.setPhase(atlasMap, v1alpha1.AtlasMapPhasePhaseUndeployed)
 	} else if *updatedDeployment.Spec.Replicas > 0 && updatedDeployment.Status.ReadyReplicas > 0 {
-		action.updatePhase(ctx, atlasMap, v1alpha1.AtlasMapPhasePhaseDeployed)
+		action.setPhase(atlasMap, v1alpha1.AtlasMapPhasePhaseDeployed)
 	} else {
-		action.updatePhase(ctx, atlasMap, v1alpha1.AtlasMapPhasePhaseDeploying)
+		action.setPhase(atlasMap, v1alpha1.AtlasMapPhasePhaseDeploying)
 	}
 
 	return nil
 }
 
-func reconcileImage(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, client client.Client) error {
+func reconcileImage(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, entry *catalog.Entry, action *deploymentAction) error {
 	container := &deployment.Spec.Template.Spec.Containers[0]
 	image := atlasMapImage(atlasMap)
+	previousImage := container.Image
+	updateDeployment := false
 
 	if container.Image != image {
 		container.Image = image
+		updateDeployment = true
+	}
 
-		// Reconcile the endpoint path for health & liveness probes
-		probePath, err := atlasMapProbePath(atlasMap)
-		if err != nil {
-			return err
+	// Reconcile the settings that depend on the AtlasMap version
+	for _, probe := range []*corev1.Probe{container.LivenessProbe, container.ReadinessProbe} {
+		if probe != nil && probe.HTTPGet != nil && probe.HTTPGet.Path != entry.ProbePath {
+			probe.HTTPGet.Path = entry.ProbePath
+			updateDeployment = true
 		}
+	}
 
-		if container.LivenessProbe.HTTPGet.Path != probePath {
-			container.LivenessProbe.HTTPGet.Path = probePath
-		}
+	if ports := atlasMapContainerPorts(entry); !equality.Semantic.DeepEqual(container.Ports, ports) {
+		container.Ports = ports
+		updateDeployment = true
+	}
 
-		if container.ReadinessProbe.HTTPGet.Path != probePath {
-			container.ReadinessProbe.HTTPGet.Path = probePath
-		}
+	if pullPolicy := atlasMapImagePullPolicy(atlasMap, image); container.ImagePullPolicy != pullPolicy {
+		container.ImagePullPolicy = pullPolicy
+		updateDeployment = true
+	}
 
-		if err := client.Update(ctx, deployment); err != nil {
-			return err
-		}
+	podSpec := &deployment.Spec.Template.Spec
+	if !equality.Semantic.DeepEqual(podSpec.ImagePullSecrets, atlasMap.Spec.ImagePullSecrets) {
+		podSpec.ImagePullSecrets = atlasMap.Spec.ImagePullSecrets
+		updateDeployment = true
 	}
 
-	if atlasMap.Status.Image != container.Image {
-		atlasMap.Status.Image = container.Image
-		if err := client.Status().Update(ctx, atlasMap); err != nil {
+	if updateDeployment {
+		if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
 			return err
 		}
+		if previousImage != image {
+			action.recorder.Eventf(atlasMap, corev1.EventTypeNormal, eventReasonImageUpdated, "Updated image from %s to %s", previousImage, image)
+		}
 	}
+
+	atlasMap.Status.Image = container.Image
+	atlasMap.Status.Version = atlasMapVersion(atlasMap)
 	return nil
 }
 
-func reconcileResources(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, client client.Client) error {
+func reconcileResources(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) error {
 	container := &deployment.Spec.Template.Spec.Containers[0]
 	updateResources, err := resources.ResourceListChanged(atlasMap, container.Resources)
 	if err != nil {
@@ -243,7 +657,7 @@ func reconcileResources(ctx context.Context, deployment *appsv1.Deployment, atla
 		if err := resources.ConfigureResources(atlasMap, container); err != nil {
 			return err
 		}
-		if err := client.Update(ctx, deployment); err != nil {
+		if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
 			return err
 		}
 	}
@@ -251,17 +665,12 @@ func reconcileResources(ctx context.Context, deployment *appsv1.Deployment, atla
 	return nil
 }
 
-func updateResourceVersion(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, client client.Client) error {
-	instance := &v1alpha1.AtlasMap{}
-
-	err := client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, instance)
-	if err != nil {
-		return err
-	}
-
-	if annotations := deployment.GetAnnotations(); annotations != nil && annotations[atlasMapVersionAnnotation] != instance.GetResourceVersion() {
-		deployment.Annotations[atlasMapVersionAnnotation] = instance.GetResourceVersion()
-		if err := client.Update(ctx, deployment); err != nil {
+func removeLegacyAnnotation(ctx context.Context, deployment *appsv1.Deployment, atlasMap *v1alpha1.AtlasMap, action *deploymentAction) error {
+	annotations := deployment.GetAnnotations()
+	if _, ok := annotations[legacyResourceVersionAnnotation]; ok {
+		delete(annotations, legacyResourceVersionAnnotation)
+		deployment.SetAnnotations(annotations)
+		if err := action.updateResource(ctx, atlasMap, deployment); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,226 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/catalog"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func getDeployment(t *testing.T, c client.Client, atlasMap *v1alpha1.AtlasMap) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, c, deployment))
+	return deployment
+}
+
+func TestDeploymentReplicas(t *testing.T) {
+	replicas := int32(3)
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Replicas = &replicas
+	action := &deploymentAction{newTestBaseAction(t, capabilities.Capabilities{})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	deployment := getDeployment(t, action.client, atlasMap)
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+
+	// Changes made directly to the Deployment are reverted
+	drift := int32(5)
+	deployment.Spec.Replicas = &drift
+	assert.NoError(t, action.client.Update(context.TODO(), deployment))
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Equal(t, int32(3), *getDeployment(t, action.client, atlasMap).Spec.Replicas)
+
+	*atlasMap.Spec.Replicas = 0
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Equal(t, int32(0), *getDeployment(t, action.client, atlasMap).Spec.Replicas)
+
+	// The autoscaler owns the replica count
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 5}
+	deployment = getDeployment(t, action.client, atlasMap)
+	deployment.Spec.Replicas = &drift
+	assert.NoError(t, action.client.Update(context.TODO(), deployment))
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Equal(t, int32(5), *getDeployment(t, action.client, atlasMap).Spec.Replicas)
+}
+
+func TestAtlasMapReplicas(t *testing.T) {
+	replicas := int32(3)
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Replicas = &replicas
+	assert.Equal(t, int32(3), *atlasMapReplicas(atlasMap))
+
+	// A new Deployment starts from the lower bound of the autoscaler
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 5}
+	assert.Equal(t, int32(1), *atlasMapReplicas(atlasMap))
+
+	minReplicas := int32(2)
+	atlasMap.Spec.Autoscaling.MinReplicas = &minReplicas
+	assert.Equal(t, int32(2), *atlasMapReplicas(atlasMap))
+}
+
+func TestDeploymentNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	selector := &v1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}
+	action := &deploymentAction{newTestBaseAction(t, capabilities.Capabilities{}, &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace},
+		Spec:       appsv1.DeploymentSpec{Selector: selector},
+	})}
+
+	// The Deployment is neither replaced nor updated
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	deployment := getDeployment(t, action.client, atlasMap)
+	assert.Equal(t, selector, deployment.Spec.Selector)
+	assert.Empty(t, deployment.Labels)
+	assert.Empty(t, deployment.OwnerReferences)
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionAvailable)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonResourceConflict, condition.Reason)
+}
+
+func TestDeploymentScheduling(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	action := &deploymentAction{newTestBaseAction(t, capabilities.Capabilities{})}
+
+	// A single replica runs without affinity
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Nil(t, getDeployment(t, action.client, atlasMap).Spec.Template.Spec.Affinity)
+
+	// Pods prefer different nodes when the autoscaler may run more than one replica
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 3}
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	podSpec := getDeployment(t, action.client, atlasMap).Spec.Template.Spec
+	assert.Equal(t, defaultAtlasMapAffinity(atlasMap), podSpec.Affinity)
+	terms := podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if assert.Len(t, terms, 1) {
+		assert.Equal(t, corev1.LabelHostname, terms[0].PodAffinityTerm.TopologyKey)
+		assert.Equal(t, atlasMapSelectorLabels(atlasMap), terms[0].PodAffinityTerm.LabelSelector.MatchLabels)
+	}
+
+	// The scheduling of the pod template replaces the default affinity rather than merging with it
+	affinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}}},
+		}}},
+	}}
+	tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "integration", Effect: corev1.TaintEffectNoSchedule}}
+	atlasMap.Spec.PodTemplate = &v1alpha1.AtlasMapPodTemplateSpec{
+		NodeSelector: map[string]string{"node-role.kubernetes.io/worker": ""},
+		Tolerations:  tolerations,
+		Affinity:     affinity,
+	}
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	podSpec = getDeployment(t, action.client, atlasMap).Spec.Template.Spec
+	assert.Equal(t, map[string]string{"node-role.kubernetes.io/worker": ""}, podSpec.NodeSelector)
+	assert.Equal(t, tolerations, podSpec.Tolerations)
+	assert.Equal(t, affinity, podSpec.Affinity)
+
+	// Clearing the pod template restores the default affinity and removes the node selector and tolerations
+	atlasMap.Spec.PodTemplate = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	podSpec = getDeployment(t, action.client, atlasMap).Spec.Template.Spec
+	assert.Empty(t, podSpec.NodeSelector)
+	assert.Empty(t, podSpec.Tolerations)
+	assert.Equal(t, defaultAtlasMapAffinity(atlasMap), podSpec.Affinity)
+}
+
+func TestDeploymentConfigHash(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.EnvFrom = []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}}}
+	atlasMap.Spec.Env = []corev1.EnvVar{{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}, Key: "password"}}}}
+	settings := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "settings", Namespace: atlasMap.Namespace}, Data: map[string]string{"LOG_LEVEL": "INFO"}}
+	credentials := &corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: "credentials", Namespace: atlasMap.Namespace}, Data: map[string][]byte{"password": []byte("secret")}}
+	other := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: atlasMap.Namespace}, Data: map[string]string{"key": "value"}}
+	action := &deploymentAction{newTestBaseAction(t, capabilities.Capabilities{}, settings, credentials, other)}
+	configHash := func() string {
+		assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+		return getDeployment(t, action.client, atlasMap).Spec.Template.Annotations[configHashAnnotation]
+	}
+
+	assert.Equal(t, []string{"settings"}, ReferencedConfigMaps(atlasMap))
+	assert.Equal(t, []string{"credentials"}, ReferencedSecrets(atlasMap))
+	hash := configHash()
+	assert.NotEmpty(t, hash)
+
+	// Changing an unrelated ConfigMap does not roll the pods
+	other.Data["key"] = "changed"
+	assert.NoError(t, action.client.Update(context.TODO(), other))
+	assert.Equal(t, hash, configHash())
+
+	settings.Data["LOG_LEVEL"] = "DEBUG"
+	assert.NoError(t, action.client.Update(context.TODO(), settings))
+	changed := configHash()
+	assert.NotEqual(t, hash, changed)
+
+	credentials.Data["password"] = []byte("changed")
+	assert.NoError(t, action.client.Update(context.TODO(), credentials))
+	assert.NotEqual(t, changed, configHash())
+}
+
+func TestConfigureContainerEnvJavaOptions(t *testing.T) {
+	entry := &catalog.Entry{JavaOptions: "-Xmx512m"}
+	fromSecret := &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "options"}}
+
+	tests := []struct {
+		name     string
+		storage  bool
+		env      []corev1.EnvVar
+		expected corev1.EnvVar
+	}{
+		{name: "default options", expected: corev1.EnvVar{Name: javaOptionsEnv, Value: "-Xmx512m"}},
+		{name: "default options with storage", storage: true, expected: corev1.EnvVar{Name: javaOptionsEnv, Value: "-Xmx512m " + workspaceJavaOption}},
+		{name: "user options", env: []corev1.EnvVar{{Name: javaOptionsEnv, Value: "-Xmx1g"}},
+			expected: corev1.EnvVar{Name: javaOptionsEnv, Value: "-Xmx1g"}},
+		{name: "user options with storage", storage: true, env: []corev1.EnvVar{{Name: javaOptionsEnv, Value: "-Xmx1g"}},
+			expected: corev1.EnvVar{Name: javaOptionsEnv, Value: "-Xmx1g " + workspaceJavaOption}},
+		{name: "user options with the workspace", storage: true, env: []corev1.EnvVar{{Name: javaOptionsEnv, Value: workspaceJavaOption + " -Xmx1g"}},
+			expected: corev1.EnvVar{Name: javaOptionsEnv, Value: workspaceJavaOption + " -Xmx1g"}},
+		{name: "user options from a reference", env: []corev1.EnvVar{{Name: javaOptionsEnv, ValueFrom: fromSecret}},
+			expected: corev1.EnvVar{Name: javaOptionsEnv, ValueFrom: fromSecret}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atlasMap := newTestAtlasMap()
+			atlasMap.Spec.Env = test.env
+			if test.storage {
+				atlasMap.Spec.Storage = &v1alpha1.AtlasMapStorageSpec{}
+			}
+			container := &corev1.Container{}
+			assert.True(t, configureContainerEnv(container, atlasMap, entry, false))
+			assert.Equal(t, []corev1.EnvVar{test.expected}, container.Env)
+			assert.False(t, configureContainerEnv(container, atlasMap, entry, false))
+		})
+	}
+}
+
+func TestConfigureStorageFSGroup(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Storage = &v1alpha1.AtlasMapStorageSpec{}
+	newDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{}}}}}}
+	}
+
+	deployment := newDeployment()
+	assert.True(t, configureStorage(deployment, atlasMap, true))
+	assert.Equal(t, int64(atlasMapFSGroup), *deployment.Spec.Template.Spec.SecurityContext.FSGroup)
+	assert.False(t, configureStorage(deployment, atlasMap, true))
+
+	// The filesystem group is removed with the storage
+	atlasMap.Spec.Storage = nil
+	assert.True(t, configureStorage(deployment, atlasMap, true))
+	assert.Nil(t, deployment.Spec.Template.Spec.SecurityContext.FSGroup)
+
+	// OpenShift assigns the filesystem group of the pods
+	atlasMap.Spec.Storage = &v1alpha1.AtlasMapStorageSpec{}
+	deployment = newDeployment()
+	configureStorage(deployment, atlasMap, false)
+	assert.Nil(t, deployment.Spec.Template.Spec.SecurityContext)
+}
@@ -0,0 +1,365 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// GatewayAPIGroup is the API group of the Gateway API resources
+	GatewayAPIGroup = "gateway.networking.k8s.io"
+
+	reasonHTTPRouteAccepted     = "HTTPRouteAccepted"
+	reasonHTTPRouteNotAccepted  = "HTTPRouteNotAccepted"
+	reasonGatewayAPIUnavailable = "GatewayAPIUnavailable"
+)
+
+// The HTTPRoute is handled as an unstructured object so that the operator does not depend on the Gateway API.
+// Its API version is the most recent one served by the cluster.
+type httpRouteAction struct {
+	baseAction
+}
+
+func newHTTPRouteAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
+	return &httpRouteAction{
+		newBaseAction(log, mgr, capabilities, "HTTPRoute"),
+	}
+}
+
+func (action *httpRouteAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	apiVersion := action.capabilities.Get().GatewayAPIVersion
+
+	if action.exposureType(atlasMap) != v1alpha1.AtlasMapExposureGatewayHTTPRoute {
+		if len(apiVersion) == 0 {
+			return nil
+		}
+		return action.removeResource(ctx, atlasMap, newHTTPRoute(apiVersion, atlasMap))
+	}
+
+	if len(apiVersion) == 0 {
+		atlasMap.Status.URL = ""
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonGatewayAPIUnavailable, "The cluster does not serve the Gateway API")
+		return nil
+	}
+
+	route := newHTTPRoute(apiVersion, atlasMap)
+	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, route)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	spec := createAtlasMapHTTPRouteSpec(atlasMap)
+
+	if errors.IsNotFound(err) {
+		route = newHTTPRoute(apiVersion, atlasMap)
+		route.SetLabels(atlasMapLabels(atlasMap))
+		route.Object["spec"] = spec
+		if err := action.deployResource(ctx, atlasMap, route); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+
+		atlasMap.Status.URL = ""
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonHTTPRouteNotAccepted, "Waiting for the HTTPRoute to be accepted by its Gateway")
+		return nil
+	}
+
+	if !action.controlsResource(atlasMap, route) {
+		atlasMap.Status.URL = ""
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonResourceConflict, "HTTPRoute "+route.GetName()+" exists and is not controlled by the AtlasMap")
+		return nil
+	}
+
+	updateRoute := mergeLabels(route, atlasMapLabels(atlasMap))
+	// Only the fields set by the operator are compared, as the API server defaults the others
+	if existing, _, _ := unstructured.NestedMap(route.Object, "spec"); !httpRouteSpecMatches(existing, spec) {
+		if existing == nil {
+			existing = map[string]interface{}{}
+		}
+		delete(existing, "hostnames")
+		for name, value := range spec {
+			existing[name] = value
+		}
+		route.Object["spec"] = existing
+		updateRoute = true
+	}
+	if updateRoute {
+		if err := action.updateResource(ctx, atlasMap, route); err != nil {
+			return err
+		}
+	}
+
+	return action.setHTTPRouteStatus(ctx, atlasMap, route, apiVersion)
+}
+
+// setHTTPRouteStatus reports whether the parent Gateway accepted the HTTPRoute and, once it did, the URL of its listener
+func (action *httpRouteAction) setHTTPRouteStatus(ctx context.Context, atlasMap *v1alpha1.AtlasMap, route *unstructured.Unstructured, apiVersion string) error {
+	reference := atlasMapGatewayReference(atlasMap)
+
+	accepted, message := httpRouteAccepted(route, reference)
+	if !accepted {
+		atlasMap.Status.URL = ""
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonHTTPRouteNotAccepted, message)
+		return nil
+	}
+
+	gateway := &unstructured.Unstructured{}
+	gateway.SetAPIVersion(apiVersion)
+	gateway.SetKind("Gateway")
+	if err := action.client.Get(ctx, types.NamespacedName{Name: reference.Name, Namespace: reference.Namespace}, gateway); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		atlasMap.Status.URL = ""
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonHTTPRouteNotAccepted, fmt.Sprintf("Gateway %s/%s not found", reference.Namespace, reference.Name))
+		return nil
+	}
+
+	atlasMap.Status.URL = gatewayListenerURL(atlasMap, gateway, reference.SectionName)
+	SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionTrue, reasonHTTPRouteAccepted,
+		fmt.Sprintf("HTTPRoute accepted by Gateway %s/%s", reference.Namespace, reference.Name))
+	return nil
+}
+
+func newHTTPRoute(apiVersion string, atlasMap *v1alpha1.AtlasMap) *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion(apiVersion)
+	route.SetKind("HTTPRoute")
+	route.SetName(atlasMap.Name)
+	route.SetNamespace(atlasMap.Namespace)
+	return route
+}
+
+// atlasMapGatewayReference returns the parent Gateway of the HTTPRoute, defaulting its namespace to the AtlasMap one
+func atlasMapGatewayReference(atlasMap *v1alpha1.AtlasMap) v1alpha1.AtlasMapGatewayReference {
+	reference := v1alpha1.AtlasMapGatewayReference{}
+	if exposure := atlasMap.Spec.Exposure; exposure != nil && exposure.Gateway != nil {
+		reference = *exposure.Gateway
+	}
+	if len(reference.Namespace) == 0 {
+		reference.Namespace = atlasMap.Namespace
+	}
+	return reference
+}
+
+// ReferencedGateway returns the namespace/name of the Gateway that the HTTPRoute of the AtlasMap is attached to,
+// or an empty string when the AtlasMap is not exposed through an HTTPRoute
+func ReferencedGateway(atlasMap *v1alpha1.AtlasMap) string {
+	if exposure := atlasMap.Spec.Exposure; exposure == nil || exposure.Type != v1alpha1.AtlasMapExposureGatewayHTTPRoute || exposure.Gateway == nil {
+		return ""
+	}
+	reference := atlasMapGatewayReference(atlasMap)
+	return reference.Namespace + "/" + reference.Name
+}
+
+func createAtlasMapHTTPRouteSpec(atlasMap *v1alpha1.AtlasMap) map[string]interface{} {
+	reference := atlasMapGatewayReference(atlasMap)
+
+	parentRef := map[string]interface{}{
+		"group":     GatewayAPIGroup,
+		"kind":      "Gateway",
+		"name":      reference.Name,
+		"namespace": reference.Namespace,
+	}
+	if len(reference.SectionName) > 0 {
+		parentRef["sectionName"] = reference.SectionName
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": []interface{}{parentRef},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":  "PathPrefix",
+							"value": "/",
+						},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": atlasMap.Name,
+						"port": int64(portAtlasMap),
+					},
+				},
+			},
+		},
+	}
+	if len(atlasMap.Spec.RouteHostName) > 0 {
+		spec["hostnames"] = []interface{}{atlasMap.Spec.RouteHostName}
+	}
+	return spec
+}
+
+// httpRouteSpecMatches compares the fields of an existing HTTPRoute spec that are set by the operator, ignoring
+// the group, kind, weight and matches defaults that the Gateway API adds to the parent and backend references
+func httpRouteSpecMatches(existing map[string]interface{}, desired map[string]interface{}) bool {
+	if existing == nil {
+		return false
+	}
+	if !equality.Semantic.DeepEqual(existing["hostnames"], desired["hostnames"]) {
+		return false
+	}
+
+	existingParents, _, _ := unstructured.NestedSlice(existing, "parentRefs")
+	desiredParents := desired["parentRefs"].([]interface{})
+	if len(existingParents) != len(desiredParents) {
+		return false
+	}
+	for i := range desiredParents {
+		if !fieldsMatch(existingParents[i], desiredParents[i], "name", "namespace", "sectionName") {
+			return false
+		}
+	}
+
+	existingRules, _, _ := unstructured.NestedSlice(existing, "rules")
+	if len(existingRules) != 1 {
+		return false
+	}
+	rule, ok := existingRules[0].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	backends, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+	desiredBackend := desired["rules"].([]interface{})[0].(map[string]interface{})["backendRefs"].([]interface{})[0]
+	if len(backends) != 1 || !fieldsMatch(backends[0], desiredBackend, "name", "port") {
+		return false
+	}
+	matches, _, _ := unstructured.NestedSlice(rule, "matches")
+	if len(matches) != 1 {
+		return false
+	}
+	match, ok := matches[0].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	path, _, _ := unstructured.NestedStringMap(match, "path")
+	return path["type"] == "PathPrefix" && path["value"] == "/"
+}
+
+func fieldsMatch(existing interface{}, desired interface{}, fields ...string) bool {
+	existingMap, ok := existing.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	desiredMap := desired.(map[string]interface{})
+	for _, name := range fields {
+		if fmt.Sprint(existingMap[name]) != fmt.Sprint(desiredMap[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// httpRouteAccepted looks up the Accepted condition that the parent Gateway reports in the HTTPRoute status
+func httpRouteAccepted(route *unstructured.Unstructured, reference v1alpha1.AtlasMapGatewayReference) (bool, string) {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	for _, parent := range parents {
+		parentStatus, ok := parent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parentRef, _, _ := unstructured.NestedStringMap(parentStatus, "parentRef")
+		namespace := parentRef["namespace"]
+		if len(namespace) == 0 {
+			namespace = route.GetNamespace()
+		}
+		if parentRef["name"] != reference.Name || namespace != reference.Namespace {
+			continue
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(parentStatus, "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["type"] != "Accepted" {
+				continue
+			}
+			if condition["status"] == string(v1.ConditionTrue) {
+				return true, ""
+			}
+			return false, fmt.Sprintf("HTTPRoute not accepted by Gateway %s/%s: %v", reference.Namespace, reference.Name, condition["message"])
+		}
+	}
+	return false, fmt.Sprintf("Waiting for the HTTPRoute to be accepted by Gateway %s/%s", reference.Namespace, reference.Name)
+}
+
+// gatewayListenerURL returns the URL of the first HTTP or HTTPS listener of the Gateway that accepts the HTTPRoute.
+// The host is the route host name, else the listener host name, else the address assigned to the Gateway
+func gatewayListenerURL(atlasMap *v1alpha1.AtlasMap, gateway *unstructured.Unstructured, sectionName string) string {
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	for _, l := range listeners {
+		listener, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(listener, "name")
+		protocol, _, _ := unstructured.NestedString(listener, "protocol")
+		if (len(sectionName) > 0 && name != sectionName) || (protocol != "HTTP" && protocol != "HTTPS") {
+			continue
+		}
+		if !gatewayListenerAccepted(gateway, name) {
+			continue
+		}
+
+		host := atlasMap.Spec.RouteHostName
+		if len(host) == 0 {
+			if hostname, _, _ := unstructured.NestedString(listener, "hostname"); len(hostname) > 0 && !strings.HasPrefix(hostname, "*") {
+				host = hostname
+			}
+		}
+		if len(host) == 0 {
+			addresses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "addresses")
+			for _, a := range addresses {
+				if address, ok := a.(map[string]interface{}); ok {
+					if value, _, _ := unstructured.NestedString(address, "value"); len(value) > 0 {
+						host = value
+						break
+					}
+				}
+			}
+		}
+		if len(host) == 0 {
+			continue
+		}
+
+		scheme, defaultPort := "http", int64(80)
+		if protocol == "HTTPS" {
+			scheme, defaultPort = "https", int64(443)
+		}
+		if port, _, _ := unstructured.NestedInt64(listener, "port"); port > 0 && port != defaultPort {
+			return fmt.Sprintf("%s://%s:%d", scheme, host, port)
+		}
+		return fmt.Sprintf("%s://%s", scheme, host)
+	}
+	return ""
+}
+
+// gatewayListenerAccepted checks the Accepted condition of a Gateway listener, considering listeners
+// that the Gateway does not report on yet as accepted
+func gatewayListenerAccepted(gateway *unstructured.Unstructured, name string) bool {
+	statuses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "listeners")
+	for _, s := range statuses {
+		status, ok := s.(map[string]interface{})
+		if !ok || status["name"] != name {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+		for _, c := range conditions {
+			if condition, ok := c.(map[string]interface{}); ok && condition["type"] == "Accepted" {
+				return condition["status"] == string(v1.ConditionTrue)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testGatewayAPIVersion = "gateway.networking.k8s.io/v1beta1"
+
+func newTestGateway() *unstructured.Unstructured {
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "http", "protocol": "HTTP", "port": int64(8080)},
+			},
+		},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"value": "192.168.1.10"}},
+		},
+	}}
+	gateway.SetAPIVersion(testGatewayAPIVersion)
+	gateway.SetKind("Gateway")
+	gateway.SetName("gateway")
+	gateway.SetNamespace("infra")
+	return gateway
+}
+
+func TestHTTPRoute(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.RouteHostName = "atlasmap.example.com"
+	atlasMap.Spec.Exposure = &v1alpha1.AtlasMapExposureSpec{
+		Type:    v1alpha1.AtlasMapExposureGatewayHTTPRoute,
+		Gateway: &v1alpha1.AtlasMapGatewayReference{Name: "gateway", Namespace: "infra", SectionName: "http"},
+	}
+	action := &httpRouteAction{newTestBaseAction(t, capabilities.Capabilities{GatewayAPIVersion: testGatewayAPIVersion}, newTestGateway())}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	route := newHTTPRoute(testGatewayAPIVersion, atlasMap)
+	assert.True(t, exists(t, action.client, route))
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"group": GatewayAPIGroup, "kind": "Gateway", "name": "gateway", "namespace": "infra", "sectionName": "http",
+	}}, parentRefs)
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	assert.Equal(t, []string{"atlasmap.example.com"}, hostnames)
+	assert.Equal(t, reasonHTTPRouteNotAccepted, meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady).Reason)
+
+	// The Gateway reports the HTTPRoute as accepted
+	assert.NoError(t, unstructured.SetNestedSlice(route.Object, []interface{}{
+		map[string]interface{}{
+			"parentRef":  map[string]interface{}{"name": "gateway", "namespace": "infra"},
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}},
+		},
+	}, "status", "parents"))
+	assert.NoError(t, action.client.Update(context.TODO(), route))
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady)
+	assert.Equal(t, v1.ConditionTrue, condition.Status)
+	assert.Equal(t, reasonHTTPRouteAccepted, condition.Reason)
+	assert.Equal(t, "http://atlasmap.example.com:8080", atlasMap.Status.URL)
+
+	// The host name falls back to the address of the Gateway once cleared
+	atlasMap.Spec.RouteHostName = ""
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.True(t, exists(t, action.client, route))
+	_, found, _ := unstructured.NestedSlice(route.Object, "spec", "hostnames")
+	assert.False(t, found)
+	assert.Equal(t, "http://192.168.1.10:8080", atlasMap.Status.URL)
+
+	atlasMap.Spec.Exposure = &v1alpha1.AtlasMapExposureSpec{Type: v1alpha1.AtlasMapExposureIngress}
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.False(t, exists(t, action.client, newHTTPRoute(testGatewayAPIVersion, atlasMap)))
+}
+
+func TestHTTPRouteNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Exposure = &v1alpha1.AtlasMapExposureSpec{
+		Type:    v1alpha1.AtlasMapExposureGatewayHTTPRoute,
+		Gateway: &v1alpha1.AtlasMapGatewayReference{Name: "gateway"},
+	}
+	spec := map[string]interface{}{"parentRefs": []interface{}{map[string]interface{}{"name": "other"}}}
+	route := newHTTPRoute(testGatewayAPIVersion, atlasMap)
+	route.Object["spec"] = spec
+	action := &httpRouteAction{newTestBaseAction(t, capabilities.Capabilities{GatewayAPIVersion: testGatewayAPIVersion}, route)}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Equal(t, reasonResourceConflict, meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady).Reason)
+	route = newHTTPRoute(testGatewayAPIVersion, atlasMap)
+	assert.True(t, exists(t, action.client, route))
+	assert.Equal(t, spec, route.Object["spec"])
+
+	atlasMap.Spec.Exposure = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.True(t, exists(t, action.client, route))
+}
+
+func TestReferencedGateway(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	assert.Empty(t, ReferencedGateway(atlasMap))
+
+	atlasMap.Spec.Exposure = &v1alpha1.AtlasMapExposureSpec{
+		Type:    v1alpha1.AtlasMapExposureGatewayHTTPRoute,
+		Gateway: &v1alpha1.AtlasMapGatewayReference{Name: "gateway"},
+	}
+	assert.Equal(t, "test/gateway", ReferencedGateway(atlasMap))
+}
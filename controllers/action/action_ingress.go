@@ -3,30 +3,45 @@ package action
 import (
 	"context"
 
-	"sigs.k8s.io/controller-runtime/pkg/client"
-
 	netv1 "k8s.io/api/networking/v1"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
 	"github.com/atlasmap/atlasmap-operator/controllers/util"
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+const (
+	reasonIngressConfigured  = "IngressConfigured"
+	reasonIngressUnavailable = "IngressUnavailable"
+)
+
 type ingressAction struct {
 	baseAction
 }
 
-func newIngressAction(log logr.Logger, mgr manager.Manager) Action {
+func newIngressAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
 	return &ingressAction{
-		newBaseAction(log, mgr, "Ingress"),
+		newBaseAction(log, mgr, capabilities, "Ingress"),
 	}
 }
 
 func (action *ingressAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	if action.exposureType(atlasMap) != v1alpha1.AtlasMapExposureIngress {
+		return action.removeResource(ctx, atlasMap, &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}})
+	}
+
+	if action.capabilities.Get().IngressAPIVersion != netv1.SchemeGroupVersion.String() {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonIngressUnavailable,
+			"The cluster does not serve "+netv1.SchemeGroupVersion.String()+" Ingresses")
+		return nil
+	}
+
 	ingress := &netv1.Ingress{}
 
 	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, ingress)
@@ -36,18 +51,44 @@ func (action *ingressAction) Handle(ctx context.Context, atlasMap *v1alpha1.Atla
 			return err
 		}
 	} else if err == nil && ingress != nil {
-		if err := reconcileIngress(ctx, ingress, atlasMap, action.client); err != nil {
+		if !action.controlsResource(atlasMap, ingress) {
+			atlasMap.Status.URL = ""
+			SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonResourceConflict, "Ingress "+ingress.Name+" exists and is not controlled by the AtlasMap")
+			return nil
+		}
+		if err := reconcileIngress(ctx, ingress, atlasMap, action); err != nil {
 			return err
 		}
 	} else {
 		return err
 	}
 
+	host := util.GetIngressHostNameFor(atlasMap)
+	atlasMap.Status.URL = ingressURL(atlasMap)
+	SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionTrue, reasonIngressConfigured, "Ingress configured for host "+host)
+
 	return nil
 }
 
 func createIngress(atlasMap *v1alpha1.AtlasMap) *netv1.Ingress {
-	return &netv1.Ingress{
+	var className *string
+	var tls []netv1.IngressTLS
+
+	if spec := atlasMap.Spec.Ingress; spec != nil {
+		if len(spec.ClassName) > 0 {
+			className = &spec.ClassName
+		}
+		if len(spec.TLSSecretName) > 0 {
+			tls = []netv1.IngressTLS{
+				{
+					Hosts:      []string{util.GetIngressHostNameFor(atlasMap)},
+					SecretName: spec.TLSSecretName,
+				},
+			}
+		}
+	}
+
+	ingress := &netv1.Ingress{
 		TypeMeta: v1.TypeMeta{
 			Kind:       "Ingress",
 			APIVersion: netv1.SchemeGroupVersion.String(),
@@ -58,41 +99,109 @@ func createIngress(atlasMap *v1alpha1.AtlasMap) *netv1.Ingress {
 			Labels:    atlasMapLabels(atlasMap),
 		},
 		Spec: netv1.IngressSpec{
-			DefaultBackend: &netv1.IngressBackend{
-				Service: &netv1.IngressServiceBackend{
-					Name: atlasMap.Name,
-					Port: netv1.ServiceBackendPort{
-						Name:   "port",
-						Number: portAtlasMap,
+			IngressClassName: className,
+			TLS:              tls,
+			Rules:            createIngressRules(atlasMap),
+		},
+	}
+	reconcileAnnotations(ingress, atlasMapIngressAnnotations(atlasMap))
+	return ingress
+}
+
+// atlasMapIngressAnnotations returns the annotations of the AtlasMap Ingress
+func atlasMapIngressAnnotations(atlasMap *v1alpha1.AtlasMap) map[string]string {
+	if spec := atlasMap.Spec.Ingress; spec != nil {
+		return spec.Annotations
+	}
+	return nil
+}
+
+func createIngressRules(atlasMap *v1alpha1.AtlasMap) []netv1.IngressRule {
+	path, pathType := "/", netv1.PathTypePrefix
+	if spec := atlasMap.Spec.Ingress; spec != nil {
+		if len(spec.Path) > 0 {
+			path = spec.Path
+		}
+		if len(spec.PathType) > 0 {
+			pathType = spec.PathType
+		}
+	}
+
+	return []netv1.IngressRule{
+		{
+			Host: util.GetIngressHostNameFor(atlasMap),
+			IngressRuleValue: netv1.IngressRuleValue{
+				HTTP: &netv1.HTTPIngressRuleValue{
+					Paths: []netv1.HTTPIngressPath{
+						{
+							Path:     path,
+							PathType: &pathType,
+							Backend:  atlasMapIngressBackend(atlasMap),
+						},
 					},
 				},
 			},
-			Rules: []netv1.IngressRule{
-				{
-					Host: util.GetIngressHostNameFor(atlasMap),
-				},
+		},
+	}
+}
+
+func atlasMapIngressBackend(atlasMap *v1alpha1.AtlasMap) netv1.IngressBackend {
+	return netv1.IngressBackend{
+		Service: &netv1.IngressServiceBackend{
+			Name: atlasMap.Name,
+			// The port is referenced by the name of the Service port, as networking/v1 does not allow setting both a name and a number
+			Port: netv1.ServiceBackendPort{
+				Name: "http",
 			},
 		},
 	}
 }
 
-func reconcileIngress(ctx context.Context, ingress *netv1.Ingress, atlasMap *v1alpha1.AtlasMap, client client.Client) error {
-	if len(ingress.Spec.Rules) == 1 {
-		host := util.GetIngressHostNameFor(atlasMap)
-		if host != ingress.Spec.Rules[0].Host {
-			ingress.Spec.Rules[0].Host = host
-			if err := client.Update(ctx, ingress); err != nil {
-				return err
-			}
-		}
+// ingressURL returns the URL of AtlasMap on the Ingress host, served over HTTPS when a TLS secret is configured
+func ingressURL(atlasMap *v1alpha1.AtlasMap) string {
+	scheme := "http"
+	if spec := atlasMap.Spec.Ingress; spec != nil && len(spec.TLSSecretName) > 0 {
+		scheme = "https"
+	}
+	url := scheme + "://" + util.GetIngressHostNameFor(atlasMap)
+	if spec := atlasMap.Spec.Ingress; spec != nil && len(spec.Path) > 0 && spec.Path != "/" {
+		url += spec.Path
+	}
+	return url
+}
 
-		url := "http://" + ingress.Spec.Rules[0].Host
-		if atlasMap.Status.URL != url {
-			atlasMap.Status.URL = url
-			if err := client.Status().Update(ctx, atlasMap); err != nil {
-				return err
-			}
-		}
+// reconcileIngress reverts any drift from the desired class, TLS, rules, backend, labels and annotations of the Ingress
+func reconcileIngress(ctx context.Context, ingress *netv1.Ingress, atlasMap *v1alpha1.AtlasMap, action *ingressAction) error {
+	desired := createIngress(atlasMap)
+	updateIngress := mergeLabels(ingress, desired.Labels)
+
+	if reconcileAnnotations(ingress, atlasMapIngressAnnotations(atlasMap)) {
+		updateIngress = true
+	}
+
+	// Earlier operator versions routed every request through an invalid default backend, which is superseded by the rules
+	if ingress.Spec.DefaultBackend != nil {
+		ingress.Spec.DefaultBackend = nil
+		updateIngress = true
+	}
+
+	if !equality.Semantic.DeepEqual(ingress.Spec.IngressClassName, desired.Spec.IngressClassName) {
+		ingress.Spec.IngressClassName = desired.Spec.IngressClassName
+		updateIngress = true
+	}
+
+	if !equality.Semantic.DeepEqual(ingress.Spec.TLS, desired.Spec.TLS) {
+		ingress.Spec.TLS = desired.Spec.TLS
+		updateIngress = true
+	}
+
+	if !equality.Semantic.DeepEqual(ingress.Spec.Rules, desired.Spec.Rules) {
+		ingress.Spec.Rules = desired.Spec.Rules
+		updateIngress = true
+	}
+
+	if updateIngress {
+		return action.updateResource(ctx, atlasMap, ingress)
 	}
 	return nil
 }
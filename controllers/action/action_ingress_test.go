@@ -0,0 +1,122 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestIngressAPIVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		ingressAPIVersion string
+		exists            bool
+		reason            string
+	}{
+		{name: "creates networking.k8s.io/v1 Ingresses", ingressAPIVersion: "networking.k8s.io/v1", exists: true, reason: reasonIngressConfigured},
+		{name: "reports older Ingress APIs", ingressAPIVersion: "networking.k8s.io/v1beta1", exists: false, reason: reasonIngressUnavailable},
+		{name: "reports missing Ingress API", exists: false, reason: reasonIngressUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atlasMap := newTestAtlasMap()
+			action := &ingressAction{newTestBaseAction(t, capabilities.Capabilities{IngressAPIVersion: test.ingressAPIVersion})}
+			assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+			assert.Equal(t, test.exists, exists(t, action.client, &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}))
+			condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, test.reason, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestIngressSpec(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.RouteHostName = "atlasmap.example.com"
+	atlasMap.Spec.Ingress = &v1alpha1.AtlasMapIngressSpec{
+		ClassName:     "nginx",
+		TLSSecretName: "atlasmap-tls",
+		Annotations:   map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "10m"},
+		Path:          "/atlasmap",
+		PathType:      netv1.PathTypeExact,
+	}
+
+	action := &ingressAction{newTestBaseAction(t, capabilities.Capabilities{IngressAPIVersion: netv1.SchemeGroupVersion.String()})}
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+	ingress := &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, ingress))
+	assert.Equal(t, "nginx", *ingress.Spec.IngressClassName)
+	assert.Equal(t, []netv1.IngressTLS{{Hosts: []string{"atlasmap.example.com"}, SecretName: "atlasmap-tls"}}, ingress.Spec.TLS)
+	assert.Equal(t, "10m", ingress.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"])
+	path := ingress.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, "/atlasmap", path.Path)
+	assert.Equal(t, netv1.PathTypeExact, *path.PathType)
+	assert.Equal(t, "https://atlasmap.example.com/atlasmap", atlasMap.Status.URL)
+
+	// Clearing the Ingress settings reverts the class, TLS, annotations and path to their defaults
+	ingress.Annotations["example.com/other"] = "kept"
+	assert.NoError(t, action.client.Update(context.TODO(), ingress))
+
+	atlasMap.Spec.Ingress = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+	ingress = &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, ingress))
+	assert.Nil(t, ingress.Spec.IngressClassName)
+	assert.Empty(t, ingress.Spec.TLS)
+	assert.Equal(t, map[string]string{"example.com/other": "kept"}, ingress.Annotations)
+	path = ingress.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, "/", path.Path)
+	assert.Equal(t, netv1.PathTypePrefix, *path.PathType)
+	assert.Equal(t, "http://atlasmap.example.com", atlasMap.Status.URL)
+}
+
+func TestIngressNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Ingress = &v1alpha1.AtlasMapIngressSpec{ClassName: "nginx"}
+	action := &ingressAction{newTestBaseAction(t, capabilities.Capabilities{IngressAPIVersion: netv1.SchemeGroupVersion.String()}, &netv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace},
+	})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	ingress := &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, ingress))
+	assert.Nil(t, ingress.Spec.IngressClassName)
+	assert.Empty(t, ingress.OwnerReferences)
+	assert.Empty(t, atlasMap.Status.URL)
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, v1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonResourceConflict, condition.Reason)
+	}
+}
+
+func TestIngressBackend(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	legacyBackend := &netv1.IngressBackend{Service: &netv1.IngressServiceBackend{Name: atlasMap.Name, Port: netv1.ServiceBackendPort{Name: "port", Number: portAtlasMap}}}
+	ingress := &netv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace},
+		Spec:       netv1.IngressSpec{DefaultBackend: legacyBackend},
+	}
+	action := &ingressAction{newTestBaseAction(t, capabilities.Capabilities{IngressAPIVersion: netv1.SchemeGroupVersion.String()})}
+	assert.NoError(t, controllerutil.SetControllerReference(atlasMap, ingress, action.scheme))
+	assert.NoError(t, action.client.Create(context.TODO(), ingress))
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+	ingress = &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, ingress))
+	assert.Nil(t, ingress.Spec.DefaultBackend)
+	backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend
+	assert.Equal(t, &netv1.IngressServiceBackend{Name: atlasMap.Name, Port: netv1.ServiceBackendPort{Name: "http"}}, backend.Service)
+}
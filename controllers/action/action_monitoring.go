@@ -0,0 +1,162 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// MonitoringAPIVersion is the API version of the Prometheus Operator resources
+	MonitoringAPIVersion  = "monitoring.coreos.com/v1"
+	defaultScrapeInterval = "30s"
+)
+
+// The ServiceMonitor and PrometheusRule are handled as unstructured objects so that the operator does not
+// depend on the Prometheus Operator API. They are only managed when the cluster serves that API.
+type monitoringAction struct {
+	baseAction
+}
+
+func newMonitoringAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
+	return &monitoringAction{
+		newBaseAction(log, mgr, capabilities, "Monitoring"),
+	}
+}
+
+func (action *monitoringAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	if !action.capabilities.Get().Monitoring {
+		return nil
+	}
+
+	var serviceMonitorSpec, prometheusRuleSpec map[string]interface{}
+	if monitoring := atlasMap.Spec.Monitoring; monitoring != nil {
+		serviceMonitorSpec = createAtlasMapServiceMonitorSpec(atlasMap)
+		if monitoring.Alerts == nil || *monitoring.Alerts {
+			prometheusRuleSpec = createAtlasMapPrometheusRuleSpec(atlasMap)
+		}
+	}
+
+	if err := action.reconcileObject(ctx, atlasMap, "ServiceMonitor", serviceMonitorSpec); err != nil {
+		return err
+	}
+	return action.reconcileObject(ctx, atlasMap, "PrometheusRule", prometheusRuleSpec)
+}
+
+// reconcileObject creates or updates the monitoring resource of the given kind, or deletes it when there is no desired spec
+func (action *monitoringAction) reconcileObject(ctx context.Context, atlasMap *v1alpha1.AtlasMap, kind string, spec map[string]interface{}) error {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(MonitoringAPIVersion)
+	object.SetKind(kind)
+
+	object.SetName(atlasMap.Name)
+	object.SetNamespace(atlasMap.Namespace)
+
+	if spec == nil {
+		return action.removeResource(ctx, atlasMap, object)
+	}
+
+	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, object)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+	if exists && !action.controlsResource(atlasMap, object) {
+		return nil
+	}
+
+	labels := atlasMapLabels(atlasMap)
+	for name, value := range atlasMap.Spec.Monitoring.Labels {
+		labels[name] = value
+	}
+
+	if !exists {
+		object.SetLabels(labels)
+		object.Object["spec"] = spec
+		return action.deployResource(ctx, atlasMap, object)
+	}
+
+	updateObject := mergeLabels(object, labels)
+	if !equality.Semantic.DeepEqual(object.Object["spec"], spec) {
+		object.Object["spec"] = spec
+		updateObject = true
+	}
+
+	if updateObject {
+		return action.updateResource(ctx, atlasMap, object)
+	}
+	return nil
+}
+
+func createAtlasMapServiceMonitorSpec(atlasMap *v1alpha1.AtlasMap) map[string]interface{} {
+	interval := defaultScrapeInterval
+	if len(atlasMap.Spec.Monitoring.Interval) > 0 {
+		interval = atlasMap.Spec.Monitoring.Interval
+	}
+
+	matchLabels := map[string]interface{}{}
+	for name, value := range atlasMapSelectorLabels(atlasMap) {
+		matchLabels[name] = value
+	}
+
+	return map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{atlasMap.Namespace},
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port":     "prometheus",
+				"interval": interval,
+			},
+		},
+	}
+}
+
+func createAtlasMapPrometheusRuleSpec(atlasMap *v1alpha1.AtlasMap) map[string]interface{} {
+	deployment := fmt.Sprintf(`namespace="%s",deployment="%s"`, atlasMap.Namespace, atlasMap.Name)
+	pods := fmt.Sprintf(`namespace="%s",pod=~"%s-[a-z0-9]+-[a-z0-9]+",container="atlasmap"`, atlasMap.Namespace, atlasMap.Name)
+
+	return map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name": "atlasmap." + atlasMap.Name,
+				"rules": []interface{}{
+					alertingRule("AtlasMapUnavailable", "critical",
+						fmt.Sprintf("kube_deployment_status_replicas_available{%s} == 0 and kube_deployment_spec_replicas{%s} > 0", deployment, deployment),
+						"AtlasMap is unavailable",
+						fmt.Sprintf("AtlasMap %s/%s has had no available pods for more than 5 minutes.", atlasMap.Namespace, atlasMap.Name)),
+					alertingRule("AtlasMapPodRestarting", "warning",
+						fmt.Sprintf("increase(kube_pod_container_status_restarts_total{%s}[15m]) > 2", pods),
+						"AtlasMap pod is restarting",
+						fmt.Sprintf("A pod of AtlasMap %s/%s restarted more than twice in the last 15 minutes.", atlasMap.Namespace, atlasMap.Name)),
+				},
+			},
+		},
+	}
+}
+
+func alertingRule(alert string, severity string, expr string, summary string, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"alert": alert,
+		"expr":  expr,
+		"for":   "5m",
+		"labels": map[string]interface{}{
+			"severity": severity,
+		},
+		"annotations": map[string]interface{}{
+			"summary":     summary,
+			"description": description,
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newMonitoringObject(atlasMap *v1alpha1.AtlasMap, kind string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(MonitoringAPIVersion)
+	object.SetKind(kind)
+	object.SetName(atlasMap.Name)
+	object.SetNamespace(atlasMap.Namespace)
+	return object
+}
+
+func TestMonitoring(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Monitoring = &v1alpha1.AtlasMapMonitoringSpec{}
+	action := &monitoringAction{newTestBaseAction(t, capabilities.Capabilities{Monitoring: true})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	serviceMonitor := newMonitoringObject(atlasMap, "ServiceMonitor")
+	assert.True(t, exists(t, action.client, serviceMonitor))
+	assert.Equal(t, createAtlasMapServiceMonitorSpec(atlasMap)["endpoints"], serviceMonitor.Object["spec"].(map[string]interface{})["endpoints"])
+	assert.True(t, exists(t, action.client, newMonitoringObject(atlasMap, "PrometheusRule")))
+
+	atlasMap.Spec.Monitoring = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.False(t, exists(t, action.client, newMonitoringObject(atlasMap, "ServiceMonitor")))
+	assert.False(t, exists(t, action.client, newMonitoringObject(atlasMap, "PrometheusRule")))
+}
+
+func TestMonitoringNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	spec := map[string]interface{}{"endpoints": []interface{}{}}
+	serviceMonitor := newMonitoringObject(atlasMap, "ServiceMonitor")
+	serviceMonitor.Object["spec"] = spec
+	action := &monitoringAction{newTestBaseAction(t, capabilities.Capabilities{Monitoring: true}, serviceMonitor)}
+
+	// A ServiceMonitor of the same name is neither updated nor removed
+	atlasMap.Spec.Monitoring = &v1alpha1.AtlasMapMonitoringSpec{}
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	serviceMonitor = newMonitoringObject(atlasMap, "ServiceMonitor")
+	assert.True(t, exists(t, action.client, serviceMonitor))
+	assert.Equal(t, spec, serviceMonitor.Object["spec"])
+
+	atlasMap.Spec.Monitoring = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.True(t, exists(t, action.client, newMonitoringObject(atlasMap, "ServiceMonitor")))
+}
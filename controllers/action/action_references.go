@@ -0,0 +1,126 @@
+package action
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// configHashAnnotation holds the hash of the ConfigMaps and Secrets used by the AtlasMap pods, so that
+// changing any of them rolls the pods
+const configHashAnnotation = "atlasmap.io/config-hash"
+
+// ReferencedConfigMaps returns the names of the ConfigMaps, in the AtlasMap namespace, that the AtlasMap pods use
+func ReferencedConfigMaps(atlasMap *v1alpha1.AtlasMap) []string {
+	names := map[string]bool{}
+	if len(atlasMap.Spec.ApplicationProperties) > 0 {
+		names[atlasMapConfigMapName(atlasMap)] = true
+	}
+	for _, env := range atlasMap.Spec.Env {
+		if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+			names[env.ValueFrom.ConfigMapKeyRef.Name] = true
+		}
+	}
+	for _, envFrom := range atlasMap.Spec.EnvFrom {
+		if envFrom.ConfigMapRef != nil {
+			names[envFrom.ConfigMapRef.Name] = true
+		}
+	}
+	return sortedNames(names)
+}
+
+// ReferencedSecrets returns the names of the Secrets, in the AtlasMap namespace, that the AtlasMap pods or Route use
+func ReferencedSecrets(atlasMap *v1alpha1.AtlasMap) []string {
+	names := podSecrets(atlasMap)
+	if route := atlasMap.Spec.Route; route != nil && route.TLS != nil && len(route.TLS.CertificateSecretName) > 0 {
+		names[route.TLS.CertificateSecretName] = true
+	}
+	return sortedNames(names)
+}
+
+// podSecrets returns the names of the Secrets that the AtlasMap pods use
+func podSecrets(atlasMap *v1alpha1.AtlasMap) map[string]bool {
+	names := map[string]bool{}
+	for _, env := range atlasMap.Spec.Env {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+			names[env.ValueFrom.SecretKeyRef.Name] = true
+		}
+	}
+	for _, envFrom := range atlasMap.Spec.EnvFrom {
+		if envFrom.SecretRef != nil {
+			names[envFrom.SecretRef.Name] = true
+		}
+	}
+	if route := atlasMap.Spec.Route; route != nil && route.TLS != nil {
+		if route.TLS.Termination == routev1.TLSTerminationReencrypt || route.TLS.Termination == routev1.TLSTerminationPassthrough {
+			names[atlasMapServingCertSecretName(atlasMap)] = true
+		}
+	}
+	return names
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// configHash hashes the content of the ConfigMaps and Secrets used by the AtlasMap pods. Objects that do not
+// exist yet, like optional references, are hashed as empty
+func configHash(ctx context.Context, c client.Client, atlasMap *v1alpha1.AtlasMap) (string, error) {
+	hash := sha256.New()
+
+	for _, name := range ReferencedConfigMaps(atlasMap) {
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: atlasMap.Namespace}, configMap); err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		writeHashEntry(hash, "configmap", name)
+		for _, key := range sortedKeys(configMap.Data) {
+			writeHashEntry(hash, key, configMap.Data[key])
+		}
+		binaryData := map[string]string{}
+		for key, value := range configMap.BinaryData {
+			binaryData[key] = string(value)
+		}
+		for _, key := range sortedKeys(binaryData) {
+			writeHashEntry(hash, key, binaryData[key])
+		}
+	}
+
+	for _, name := range sortedNames(podSecrets(atlasMap)) {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: atlasMap.Namespace}, secret); err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		writeHashEntry(hash, "secret", name)
+		data := map[string]string{}
+		for key, value := range secret.Data {
+			data[key] = string(value)
+		}
+		for _, key := range sortedKeys(data) {
+			writeHashEntry(hash, key, data[key])
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeHashEntry(hash io.Writer, key string, value string) {
+	_, _ = hash.Write([]byte(key))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write([]byte(value))
+	_, _ = hash.Write([]byte{0})
+}
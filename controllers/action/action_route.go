@@ -3,42 +3,80 @@ package action
 import (
 	"context"
 
-	"sigs.k8s.io/controller-runtime/pkg/client"
-
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
 	"github.com/go-logr/logr"
 	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+const (
+	reasonRouteAdmitted           = "RouteAdmitted"
+	reasonRouteNotAdmitted        = "RouteNotAdmitted"
+	reasonRouteUnavailable        = "RouteUnavailable"
+	reasonRouteCertificateMissing = "RouteCertificateMissing"
+)
+
+// caCertificateKey is the key of the CA certificate in the Route certificate Secret, as used by cert-manager
+const caCertificateKey = "ca.crt"
+
 type routeAction struct {
 	baseAction
 }
 
-func newRouteAction(log logr.Logger, mgr manager.Manager) Action {
+func newRouteAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
 	return &routeAction{
-		newBaseAction(log, mgr, "Route"),
+		newBaseAction(log, mgr, capabilities, "Route"),
 	}
 }
 
 func (action *routeAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	if action.exposureType(atlasMap) != v1alpha1.AtlasMapExposureRoute {
+		return action.removeResource(ctx, atlasMap, &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}})
+	}
+
+	if !action.capabilities.Get().Routes {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonRouteUnavailable, "The cluster does not serve OpenShift Routes")
+		return nil
+	}
+
+	tls, err := action.routeTLSConfig(ctx, atlasMap)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonRouteCertificateMissing,
+				"Route certificate Secret "+atlasMap.Spec.Route.TLS.CertificateSecretName+" not found")
+			return nil
+		}
+		return err
+	}
+
 	route := &routev1.Route{}
 
-	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, route)
+	err = action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, route)
 	if err != nil && errors.IsNotFound(err) {
-		route = createAtlasMapRoute(atlasMap)
+		route = createAtlasMapRoute(atlasMap, tls)
 		err := action.deployResource(ctx, atlasMap, route)
 
 		// Route can take a while to create so there's a chance of an 'already exists' error occurring
 		if err != nil && !errors.IsAlreadyExists(err) {
 			return err
 		}
+
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonRouteNotAdmitted, "Waiting for the Route to be admitted")
 	} else if err == nil && route != nil {
-		if err := reconcileRoute(ctx, atlasMap, route, action.client); err != nil {
+		if !action.controlsResource(atlasMap, route) {
+			atlasMap.Status.URL = ""
+			SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonResourceConflict, "Route "+route.Name+" exists and is not controlled by the AtlasMap")
+			return nil
+		}
+		if err := reconcileRoute(ctx, atlasMap, route, tls, action); err != nil {
 			return err
 		}
 	} else {
@@ -48,25 +86,89 @@ func (action *routeAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasM
 	return nil
 }
 
-func reconcileRoute(ctx context.Context, atlasMap *v1alpha1.AtlasMap, route *routev1.Route, client client.Client) error {
+func reconcileRoute(ctx context.Context, atlasMap *v1alpha1.AtlasMap, route *routev1.Route, tls *routev1.TLSConfig, action *routeAction) error {
+	updateRoute := false
+
 	if atlasMap.Spec.RouteHostName != route.Spec.Host {
 		route.Spec.Host = atlasMap.Spec.RouteHostName
-		if err := client.Update(ctx, route); err != nil {
-			return err
-		}
+		updateRoute = true
+	}
+
+	if port := atlasMapRoutePort(); !equality.Semantic.DeepEqual(route.Spec.Port, port) {
+		route.Spec.Port = port
+		updateRoute = true
+	}
+
+	if !equality.Semantic.DeepEqual(route.Spec.TLS, tls) {
+		route.Spec.TLS = tls
+		updateRoute = true
 	}
 
-	url := "https://" + route.Spec.Host
-	if atlasMap.Status.URL != url {
-		atlasMap.Status.URL = url
-		if err := client.Status().Update(ctx, atlasMap); err != nil {
+	if updateRoute {
+		if err := action.updateResource(ctx, atlasMap, route); err != nil {
 			return err
 		}
 	}
+
+	if admitted, message := routeAdmitted(route); admitted {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionTrue, reasonRouteAdmitted, "Route admitted for host "+route.Spec.Host)
+	} else {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonRouteNotAdmitted, message)
+	}
+
+	atlasMap.Status.URL = "https://" + route.Spec.Host
 	return nil
 }
 
-func createAtlasMapRoute(atlasMap *v1alpha1.AtlasMap) *routev1.Route {
+func routeAdmitted(route *routev1.Route) (bool, string) {
+	for _, ingress := range route.Status.Ingress {
+		for _, condition := range ingress.Conditions {
+			if condition.Type != routev1.RouteAdmitted {
+				continue
+			}
+			if condition.Status == corev1.ConditionTrue {
+				return true, ""
+			}
+			return false, condition.Message
+		}
+	}
+	return false, "Waiting for the Route to be admitted"
+}
+
+// routeTLSConfig returns the TLS configuration of the Route, including the certificate read from the configured Secret
+func (action *routeAction) routeTLSConfig(ctx context.Context, atlasMap *v1alpha1.AtlasMap) (*routev1.TLSConfig, error) {
+	tls := &routev1.TLSConfig{
+		Termination: routev1.TLSTerminationEdge,
+	}
+
+	if route := atlasMap.Spec.Route; route != nil && route.TLS != nil {
+		if len(route.TLS.Termination) > 0 {
+			tls.Termination = route.TLS.Termination
+		}
+		tls.InsecureEdgeTerminationPolicy = route.TLS.InsecureEdgeTerminationPolicy
+
+		if len(route.TLS.CertificateSecretName) > 0 {
+			secret := &corev1.Secret{}
+			if err := action.client.Get(ctx, types.NamespacedName{Name: route.TLS.CertificateSecretName, Namespace: atlasMap.Namespace}, secret); err != nil {
+				return nil, err
+			}
+			tls.Certificate = string(secret.Data[corev1.TLSCertKey])
+			tls.Key = string(secret.Data[corev1.TLSPrivateKeyKey])
+			tls.CACertificate = string(secret.Data[caCertificateKey])
+		}
+	}
+
+	return tls, nil
+}
+
+// atlasMapRoutePort targets the AtlasMap port only, as the other ports of the Service do not serve AtlasMap itself
+func atlasMapRoutePort() *routev1.RoutePort {
+	return &routev1.RoutePort{
+		TargetPort: intstr.FromString("http"),
+	}
+}
+
+func createAtlasMapRoute(atlasMap *v1alpha1.AtlasMap, tls *routev1.TLSConfig) *routev1.Route {
 	return &routev1.Route{
 		TypeMeta: v1.TypeMeta{
 			Kind:       "Route",
@@ -90,9 +192,8 @@ func createAtlasMapRoute(atlasMap *v1alpha1.AtlasMap) *routev1.Route {
 				Kind: "Service",
 				Name: atlasMap.Name,
 			},
-			TLS: &routev1.TLSConfig{
-				Termination: routev1.TLSTerminationEdge,
-			},
+			Port: atlasMapRoutePort(),
+			TLS:  tls,
 		},
 	}
 }
@@ -0,0 +1,121 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRouteTLS(t *testing.T) {
+	tests := []struct {
+		termination routev1.TLSTerminationType
+		servesTLS   bool
+	}{
+		{termination: routev1.TLSTerminationEdge},
+		{termination: routev1.TLSTerminationReencrypt, servesTLS: true},
+		{termination: routev1.TLSTerminationPassthrough, servesTLS: true},
+	}
+	for _, test := range tests {
+		t.Run(string(test.termination), func(t *testing.T) {
+			atlasMap := newTestAtlasMap()
+			atlasMap.Spec.Route = &v1alpha1.AtlasMapRouteSpec{TLS: &v1alpha1.AtlasMapRouteTLSSpec{
+				Termination:                   test.termination,
+				InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+			}}
+			if test.termination != routev1.TLSTerminationPassthrough {
+				atlasMap.Spec.Route.TLS.CertificateSecretName = "route-tls"
+			}
+			base := newTestBaseAction(t, capabilities.Capabilities{Routes: true}, &corev1.Secret{
+				ObjectMeta: v1.ObjectMeta{Name: "route-tls", Namespace: atlasMap.Namespace},
+				Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key"), caCertificateKey: []byte("ca")},
+			})
+			for _, action := range []Action{&serviceAction{base}, &deploymentAction{base}, &routeAction{base}} {
+				assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+			}
+
+			route := &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+			assert.True(t, exists(t, base.client, route))
+			expected := &routev1.TLSConfig{Termination: test.termination, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect}
+			if test.termination != routev1.TLSTerminationPassthrough {
+				expected.Certificate, expected.Key, expected.CACertificate = "cert", "key", "ca"
+			}
+			assert.Equal(t, expected, route.Spec.TLS)
+
+			// The pods serve HTTPS with the serving certificate requested by the Service for reencrypt and passthrough
+			service := &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+			assert.True(t, exists(t, base.client, service))
+			podSpec := getDeployment(t, base.client, atlasMap).Spec.Template.Spec
+			container := podSpec.Containers[0]
+			if test.servesTLS {
+				assert.Equal(t, "atlasmap-tls", service.Annotations[servingCertAnnotation])
+				assert.Contains(t, podSpec.Volumes, corev1.Volume{Name: tlsVolume, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "atlasmap-tls"}}})
+				assert.Contains(t, container.VolumeMounts, corev1.VolumeMount{Name: tlsVolume, MountPath: tlsMountPath, ReadOnly: true})
+				assert.Contains(t, container.Env, corev1.EnvVar{Name: sslEnabledEnv, Value: "true"})
+				assert.Contains(t, container.Env, corev1.EnvVar{Name: sslCertificateEnv, Value: tlsMountPath + "/tls.crt"})
+				assert.Equal(t, corev1.URISchemeHTTPS, container.ReadinessProbe.HTTPGet.Scheme)
+			} else {
+				assert.NotContains(t, service.Annotations, servingCertAnnotation)
+				assert.Empty(t, podSpec.Volumes)
+				assert.Empty(t, container.VolumeMounts)
+				assert.Equal(t, corev1.URISchemeHTTP, container.ReadinessProbe.HTTPGet.Scheme)
+			}
+
+			// Edge termination reverts the pods to HTTP
+			atlasMap.Spec.Route = nil
+			for _, action := range []Action{&serviceAction{base}, &deploymentAction{base}, &routeAction{base}} {
+				assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+			}
+			route = &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+			assert.True(t, exists(t, base.client, route))
+			assert.Equal(t, &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}, route.Spec.TLS)
+			service = &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+			assert.True(t, exists(t, base.client, service))
+			assert.NotContains(t, service.Annotations, servingCertAnnotation)
+			podSpec = getDeployment(t, base.client, atlasMap).Spec.Template.Spec
+			assert.Empty(t, podSpec.Volumes)
+			assert.NotContains(t, podSpec.Containers[0].Env, corev1.EnvVar{Name: sslEnabledEnv, Value: "true"})
+			assert.Equal(t, corev1.URISchemeHTTP, podSpec.Containers[0].ReadinessProbe.HTTPGet.Scheme)
+		})
+	}
+}
+
+func TestRouteCertificateMissing(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Route = &v1alpha1.AtlasMapRouteSpec{TLS: &v1alpha1.AtlasMapRouteTLSSpec{CertificateSecretName: "route-tls"}}
+	action := &routeAction{newTestBaseAction(t, capabilities.Capabilities{Routes: true})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.False(t, exists(t, action.client, &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}))
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, reasonRouteCertificateMissing, condition.Reason)
+	}
+}
+
+func TestRouteNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.RouteHostName = "atlasmap.example.com"
+	action := &routeAction{newTestBaseAction(t, capabilities.Capabilities{Routes: true}, &routev1.Route{
+		ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace},
+		Spec:       routev1.RouteSpec{Host: "other.example.com"},
+	})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	route := &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, route))
+	assert.Equal(t, "other.example.com", route.Spec.Host)
+	assert.Nil(t, route.Spec.TLS)
+	assert.Empty(t, atlasMap.Status.URL)
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionExposureReady)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, v1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonResourceConflict, condition.Reason)
+	}
+}
@@ -2,45 +2,204 @@ package action
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// servingCertAnnotation requests OpenShift to issue a serving certificate for the Service into the named Secret
+const servingCertAnnotation = "service.beta.openshift.io/serving-cert-secret-name"
+
+const (
+	reasonLoadBalancerReady   = "LoadBalancerReady"
+	reasonLoadBalancerPending = "LoadBalancerPending"
+	reasonNotExposed          = "NotExposed"
+)
+
 type serviceAction struct {
 	baseAction
 }
 
-func newServiceAction(log logr.Logger, mgr manager.Manager) Action {
+func newServiceAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
 	return &serviceAction{
-		newBaseAction(log, mgr, "Service"),
+		newBaseAction(log, mgr, capabilities, "Service"),
 	}
 }
 
 func (action *serviceAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
 	service := &corev1.Service{}
+	exposure := action.exposureType(atlasMap)
 
 	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}, service)
 	if err != nil && errors.IsNotFound(err) {
-		service = createAtlasMapService(atlasMap)
+		service = createAtlasMapService(atlasMap, exposure, action.servesTLS(atlasMap))
 
 		if err := action.deployResource(ctx, atlasMap, service); err != nil {
 			return err
 		}
-	} else if err != nil {
+	} else if err == nil {
+		if !action.controlsResource(atlasMap, service) {
+			return nil
+		}
+		if err := reconcileService(ctx, service.DeepCopy(), atlasMap, exposure, action); err != nil {
+			return err
+		}
+	} else {
 		return err
 	}
 
+	setServiceExposureStatus(atlasMap, exposure, service)
+
 	return nil
 }
 
-func createAtlasMapService(atlasMap *v1alpha1.AtlasMap) *corev1.Service {
-	return &corev1.Service{
+// setServiceExposureStatus reports the exposure types that are provided by the Service itself
+func setServiceExposureStatus(atlasMap *v1alpha1.AtlasMap, exposure v1alpha1.AtlasMapExposureType, service *corev1.Service) {
+	switch exposure {
+	case v1alpha1.AtlasMapExposureNone:
+		atlasMap.Status.URL = fmt.Sprintf("http://%s.%s.svc:%d", atlasMap.Name, atlasMap.Namespace, portAtlasMap)
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionTrue, reasonNotExposed, "AtlasMap is only reachable within the cluster")
+	case v1alpha1.AtlasMapExposureLoadBalancer:
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			host := ingress.Hostname
+			if len(host) == 0 {
+				host = ingress.IP
+			}
+			if len(host) > 0 {
+				atlasMap.Status.URL = fmt.Sprintf("http://%s:%d", host, portAtlasMap)
+				SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionTrue, reasonLoadBalancerReady, "Load balancer assigned at "+host)
+				return
+			}
+		}
+		atlasMap.Status.URL = ""
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionExposureReady, v1.ConditionFalse, reasonLoadBalancerPending, "Waiting for a load balancer to be assigned to the Service")
+	}
+}
+
+// atlasMapServiceType returns the Service type required by the exposure type, or the one configured on the AtlasMap
+func atlasMapServiceType(atlasMap *v1alpha1.AtlasMap, exposure v1alpha1.AtlasMapExposureType) corev1.ServiceType {
+	switch exposure {
+	case v1alpha1.AtlasMapExposureNone:
+		return corev1.ServiceTypeClusterIP
+	case v1alpha1.AtlasMapExposureLoadBalancer:
+		return corev1.ServiceTypeLoadBalancer
+	}
+	if spec := atlasMap.Spec.Service; spec != nil && len(spec.Type) > 0 {
+		return spec.Type
+	}
+	return corev1.ServiceTypeClusterIP
+}
+
+// reconcileService updates the selector of Services created by earlier operator versions, which included labels
+// that change during the lifetime of the AtlasMap, and reverts any drift from the desired ports, type, labels and annotations
+func reconcileService(ctx context.Context, service *corev1.Service, atlasMap *v1alpha1.AtlasMap, exposure v1alpha1.AtlasMapExposureType, action *serviceAction) error {
+	updateService := false
+	servesTLS := action.servesTLS(atlasMap)
+	desired := createAtlasMapService(atlasMap, exposure, servesTLS)
+
+	if mergeLabels(service, desired.Labels) {
+		updateService = true
+	}
+
+	if reconcileAnnotations(service, atlasMapServiceAnnotations(atlasMap, servesTLS)) {
+		updateService = true
+	}
+
+	if !equality.Semantic.DeepEqual(service.Spec.Selector, desired.Spec.Selector) {
+		service.Spec.Selector = desired.Spec.Selector
+		updateService = true
+	}
+
+	if service.Spec.Type != desired.Spec.Type {
+		service.Spec.Type = desired.Spec.Type
+		updateService = true
+	}
+
+	// Keep the node ports allocated by the cluster, unless the Service type no longer requires them
+	ports := desired.Spec.Ports
+	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+		for i := range ports {
+			for _, port := range service.Spec.Ports {
+				if port.Name == ports[i].Name {
+					ports[i].NodePort = port.NodePort
+				}
+			}
+		}
+	}
+	if !equality.Semantic.DeepEqual(service.Spec.Ports, ports) {
+		service.Spec.Ports = ports
+		updateService = true
+	}
+
+	if updateService {
+		return action.updateResource(ctx, atlasMap, service)
+	}
+	return nil
+}
+
+// atlasMapServiceAnnotations returns the annotations of the AtlasMap Service, together with the request for a serving
+// certificate when the AtlasMap pods serve HTTPS
+func atlasMapServiceAnnotations(atlasMap *v1alpha1.AtlasMap, servesTLS bool) map[string]string {
+	annotations := map[string]string{}
+	if spec := atlasMap.Spec.Service; spec != nil {
+		for name, value := range spec.Annotations {
+			annotations[name] = value
+		}
+	}
+	if servesTLS {
+		annotations[servingCertAnnotation] = atlasMapServingCertSecretName(atlasMap)
+	}
+	return annotations
+}
+
+func createAtlasMapService(atlasMap *v1alpha1.AtlasMap, exposure v1alpha1.AtlasMapExposureType, servesTLS bool) *corev1.Service {
+	serviceType := atlasMapServiceType(atlasMap, exposure)
+	jolokia, prometheus := true, true
+
+	if spec := atlasMap.Spec.Service; spec != nil {
+		if spec.Jolokia != nil {
+			jolokia = *spec.Jolokia
+		}
+		if spec.Prometheus != nil {
+			prometheus = *spec.Prometheus
+		}
+	}
+
+	ports := []corev1.ServicePort{
+		{
+			Name:       "http",
+			Port:       portAtlasMap,
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString("http"),
+		},
+	}
+	if jolokia {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "jolokia",
+			Port:       portJolokia,
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString("jolokia"),
+		})
+	}
+	if prometheus {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "prometheus",
+			Port:       portPrometheus,
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString("prometheus"),
+		})
+	}
+
+	service := &corev1.Service{
 		TypeMeta: v1.TypeMeta{
 			APIVersion: "v1",
 			Kind:       "Service",
@@ -51,14 +210,11 @@ func createAtlasMapService(atlasMap *v1alpha1.AtlasMap) *corev1.Service {
 			Labels:    atlasMapLabels(atlasMap),
 		},
 		Spec: corev1.ServiceSpec{
-			Type:     corev1.ServiceTypeClusterIP,
-			Selector: atlasMapLabels(atlasMap),
-			Ports: []corev1.ServicePort{
-				{
-					Name: "http",
-					Port: portAtlasMap,
-				},
-			},
+			Type:     serviceType,
+			Selector: atlasMapSelectorLabels(atlasMap),
+			Ports:    ports,
 		},
 	}
+	reconcileAnnotations(service, atlasMapServiceAnnotations(atlasMap, servesTLS))
+	return service
 }
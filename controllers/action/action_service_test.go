@@ -0,0 +1,78 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceNotControlled(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	selector := map[string]string{"app": "other"}
+	action := &serviceAction{newTestBaseAction(t, capabilities.Capabilities{}, &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace},
+		Spec:       corev1.ServiceSpec{Selector: selector},
+	})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	service := &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, service))
+	assert.Equal(t, selector, service.Spec.Selector)
+	assert.Empty(t, service.OwnerReferences)
+}
+
+func TestReconcileAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  map[string]string
+		desired  map[string]string
+		expected map[string]string
+		changed  bool
+	}{
+		{name: "adds desired annotations", desired: map[string]string{"b": "2", "a": "1"},
+			expected: map[string]string{"a": "1", "b": "2", managedAnnotationsAnnotation: "a,b"}, changed: true},
+		{name: "removes annotations no longer desired", current: map[string]string{"a": "1", "b": "2", "other": "x", managedAnnotationsAnnotation: "a,b"}, desired: map[string]string{"a": "1"},
+			expected: map[string]string{"a": "1", "other": "x", managedAnnotationsAnnotation: "a"}, changed: true},
+		{name: "removes the record with the last annotation", current: map[string]string{"a": "1", "other": "x", managedAnnotationsAnnotation: "a"},
+			expected: map[string]string{"other": "x"}, changed: true},
+		{name: "reverts changed values", current: map[string]string{"a": "2", managedAnnotationsAnnotation: "a"}, desired: map[string]string{"a": "1"},
+			expected: map[string]string{"a": "1", managedAnnotationsAnnotation: "a"}, changed: true},
+		{name: "keeps annotations of other controllers", current: map[string]string{"other": "x"},
+			expected: map[string]string{"other": "x"}, changed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object := &corev1.Service{ObjectMeta: v1.ObjectMeta{Annotations: test.current}}
+			assert.Equal(t, test.changed, reconcileAnnotations(object, test.desired))
+			assert.Equal(t, test.expected, object.Annotations)
+		})
+	}
+}
+
+func TestServiceAnnotations(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Service = &v1alpha1.AtlasMapServiceSpec{Annotations: map[string]string{"example.com/team": "integration"}}
+
+	action := &serviceAction{newTestBaseAction(t, capabilities.Capabilities{})}
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+	service := &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, service))
+	assert.Equal(t, "integration", service.Annotations["example.com/team"])
+
+	// Annotations set by other controllers survive the removal of those of the AtlasMap
+	service.Annotations["example.com/other"] = "kept"
+	assert.NoError(t, action.client.Update(context.TODO(), service))
+
+	atlasMap.Spec.Service = nil
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+
+	service = &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, service))
+	assert.Equal(t, map[string]string{"example.com/other": "kept"}, service.Annotations)
+}
@@ -0,0 +1,106 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/catalog"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	reasonAsExpected          = "AsExpected"
+	reasonDeploymentReady     = "DeploymentAvailable"
+	reasonDeploymentFailed    = "DeploymentFailed"
+	reasonResourceConflict    = "ResourceConflict"
+	reasonReplacingDeployment = "ReplacingDeployment"
+	reasonRollingOut          = "RollingOut"
+	reasonRolloutComplete     = "RolloutComplete"
+	reasonScaledToZero        = "ScaledToZero"
+	reasonVersionDeprecated   = "VersionDeprecated"
+	reasonVersionSupported    = "VersionSupported"
+	reasonVersionUnsupported  = "VersionUnsupported"
+	reasonWaitingForPods      = "WaitingForPods"
+)
+
+// SetCondition records the given condition on the AtlasMap status for the current generation.
+// The status is not written to the API server.
+func SetCondition(atlasMap *v1alpha1.AtlasMap, conditionType string, status v1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&atlasMap.Status.Conditions, v1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: atlasMap.Generation,
+	})
+}
+
+// setDeploymentConditions derives the Available, Progressing and Degraded conditions from the AtlasMap Deployment
+func setDeploymentConditions(atlasMap *v1alpha1.AtlasMap, deployment *appsv1.Deployment) {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	if desired == 0 {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionAvailable, v1.ConditionFalse, reasonScaledToZero, "AtlasMap is scaled to zero replicas")
+	} else if deploymentCondition(deployment, appsv1.DeploymentAvailable) == corev1.ConditionTrue && deployment.Status.AvailableReplicas > 0 {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionAvailable, v1.ConditionTrue, reasonDeploymentReady, "AtlasMap has minimum availability")
+	} else {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionAvailable, v1.ConditionFalse, reasonWaitingForPods, "Waiting for AtlasMap pods to become available")
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation ||
+		deployment.Status.UpdatedReplicas < desired ||
+		deployment.Status.Replicas > deployment.Status.UpdatedReplicas ||
+		deployment.Status.AvailableReplicas < desired {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionProgressing, v1.ConditionTrue, reasonRollingOut, "AtlasMap Deployment rollout in progress")
+	} else {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionProgressing, v1.ConditionFalse, reasonRolloutComplete, "AtlasMap Deployment rollout complete")
+	}
+
+	if failure := deploymentFailure(deployment); failure != "" {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionDegraded, v1.ConditionTrue, reasonDeploymentFailed, failure)
+	} else {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionDegraded, v1.ConditionFalse, reasonAsExpected, "")
+	}
+}
+
+// setVersionCondition records whether the AtlasMap version is supported and reports whether it can be deployed
+func setVersionCondition(atlasMap *v1alpha1.AtlasMap, entry *catalog.Entry, err error) bool {
+	version := atlasMapVersion(atlasMap)
+	switch {
+	case err != nil:
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionVersionSupported, v1.ConditionFalse, reasonVersionUnsupported, err.Error())
+		return false
+	case entry.Status == catalog.Deprecated:
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionVersionSupported, v1.ConditionTrue, reasonVersionDeprecated, fmt.Sprintf("AtlasMap version %q is deprecated", version))
+	default:
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionVersionSupported, v1.ConditionTrue, reasonVersionSupported, "")
+	}
+	return true
+}
+
+func deploymentCondition(deployment *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) corev1.ConditionStatus {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+func deploymentFailure(deployment *appsv1.Deployment) string {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue {
+			return condition.Message
+		}
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse && condition.Reason == "ProgressDeadlineExceeded" {
+			return condition.Message
+		}
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	reasonClaimBound            = "ClaimBound"
+	reasonClaimPending          = "ClaimPending"
+	reasonClaimNotFound         = "ClaimNotFound"
+	reasonReadWriteManyRequired = "ReadWriteManyRequired"
+)
+
+// The PersistentVolumeClaim created by the operator is kept when storage is removed from the AtlasMap, so that
+// the workspace is not lost. It is garbage collected together with the AtlasMap.
+type storageAction struct {
+	baseAction
+}
+
+func newStorageAction(log logr.Logger, mgr manager.Manager, capabilities *capabilities.Detector) Action {
+	return &storageAction{
+		newBaseAction(log, mgr, capabilities, "Storage"),
+	}
+}
+
+func (action *storageAction) Handle(ctx context.Context, atlasMap *v1alpha1.AtlasMap) error {
+	storage := atlasMap.Spec.Storage
+	if storage == nil {
+		meta.RemoveStatusCondition(&atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionStorageReady)
+		return nil
+	}
+
+	claim := &corev1.PersistentVolumeClaim{}
+	err := action.client.Get(ctx, types.NamespacedName{Name: atlasMapClaimName(atlasMap), Namespace: atlasMap.Namespace}, claim)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if len(storage.ClaimName) > 0 {
+		if errors.IsNotFound(err) {
+			SetCondition(atlasMap, v1alpha1.AtlasMapConditionStorageReady, v1.ConditionFalse, reasonClaimNotFound,
+				fmt.Sprintf("PersistentVolumeClaim %s not found", storage.ClaimName))
+			return nil
+		}
+	} else if errors.IsNotFound(err) {
+		claim = createAtlasMapClaim(atlasMap)
+		if err := action.deployResource(ctx, atlasMap, claim); err != nil {
+			return err
+		}
+	} else if !action.controlsResource(atlasMap, claim) {
+		// A claim of the same name that the AtlasMap does not control may hold someone else's data
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionStorageReady, v1.ConditionFalse, reasonResourceConflict,
+			fmt.Sprintf("PersistentVolumeClaim %s exists and is not controlled by the AtlasMap", claim.Name))
+		return nil
+	} else if err := reconcileClaim(ctx, claim.DeepCopy(), atlasMap, action); err != nil {
+		return err
+	}
+
+	setStorageCondition(atlasMap, claim)
+	return nil
+}
+
+// reconcileClaim expands the PersistentVolumeClaim when its size is increased. The other fields of the claim
+// cannot change once it is created
+func reconcileClaim(ctx context.Context, claim *corev1.PersistentVolumeClaim, atlasMap *v1alpha1.AtlasMap, action *storageAction) error {
+	updateClaim := mergeLabels(claim, atlasMapLabels(atlasMap))
+
+	if size := atlasMap.Spec.Storage.Size; size != nil {
+		if current, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; !ok || size.Cmp(current) > 0 {
+			if claim.Spec.Resources.Requests == nil {
+				claim.Spec.Resources.Requests = corev1.ResourceList{}
+			}
+			claim.Spec.Resources.Requests[corev1.ResourceStorage] = *size
+			updateClaim = true
+		}
+	}
+
+	if updateClaim {
+		return action.updateResource(ctx, atlasMap, claim)
+	}
+	return nil
+}
+
+// setStorageCondition reports whether the claim is bound and can be shared by every AtlasMap replica
+func setStorageCondition(atlasMap *v1alpha1.AtlasMap, claim *corev1.PersistentVolumeClaim) {
+	if atlasMapMaxReplicas(atlasMap) > 1 && !claimAccessModes(claim)[corev1.ReadWriteMany] {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionStorageReady, v1.ConditionFalse, reasonReadWriteManyRequired,
+			fmt.Sprintf("PersistentVolumeClaim %s must be ReadWriteMany to be shared by more than one replica", claim.Name))
+		return
+	}
+
+	if claim.Status.Phase == corev1.ClaimBound {
+		SetCondition(atlasMap, v1alpha1.AtlasMapConditionStorageReady, v1.ConditionTrue, reasonClaimBound,
+			fmt.Sprintf("PersistentVolumeClaim %s is bound", claim.Name))
+		return
+	}
+	SetCondition(atlasMap, v1alpha1.AtlasMapConditionStorageReady, v1.ConditionFalse, reasonClaimPending,
+		fmt.Sprintf("Waiting for PersistentVolumeClaim %s to be bound", claim.Name))
+}
+
+// claimAccessModes returns the access modes of the bound volume, or those requested until the claim is bound
+func claimAccessModes(claim *corev1.PersistentVolumeClaim) map[corev1.PersistentVolumeAccessMode]bool {
+	accessModes := claim.Status.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = claim.Spec.AccessModes
+	}
+	modes := map[corev1.PersistentVolumeAccessMode]bool{}
+	for _, mode := range accessModes {
+		modes[mode] = true
+	}
+	return modes
+}
+
+// atlasMapClaimName is the name of the PersistentVolumeClaim holding the AtlasMap workspace
+func atlasMapClaimName(atlasMap *v1alpha1.AtlasMap) string {
+	if storage := atlasMap.Spec.Storage; storage != nil && len(storage.ClaimName) > 0 {
+		return storage.ClaimName
+	}
+	return atlasMap.Name + "-data"
+}
+
+// atlasMapStorageShared tells whether the workspace volume may be mounted by pods on different nodes at once
+func atlasMapStorageShared(atlasMap *v1alpha1.AtlasMap) bool {
+	storage := atlasMap.Spec.Storage
+	if storage == nil {
+		return true
+	}
+	if len(storage.ClaimName) > 0 {
+		// The access modes of existing claims are not known here, so they are handled as exclusive
+		return false
+	}
+	for _, mode := range storage.AccessModes {
+		if mode == corev1.ReadWriteMany {
+			return true
+		}
+	}
+	return false
+}
+
+func createAtlasMapClaim(atlasMap *v1alpha1.AtlasMap) *corev1.PersistentVolumeClaim {
+	storage := atlasMap.Spec.Storage
+
+	accessModes := storage.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+
+	requests := corev1.ResourceList{}
+	if storage.Size != nil {
+		requests[corev1.ResourceStorage] = *storage.Size
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		TypeMeta: v1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      atlasMapClaimName(atlasMap),
+			Namespace: atlasMap.Namespace,
+			Labels:    atlasMapLabels(atlasMap),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: storage.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: requests,
+			},
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func storageReason(atlasMap *v1alpha1.AtlasMap) string {
+	if condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionStorageReady); condition != nil {
+		return condition.Reason
+	}
+	return ""
+}
+
+func TestStorage(t *testing.T) {
+	size, larger := resource.MustParse("1Gi"), resource.MustParse("2Gi")
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Storage = &v1alpha1.AtlasMapStorageSpec{Size: &size}
+	base := newTestBaseAction(t, capabilities.Capabilities{})
+	handle := func() {
+		assert.NoError(t, (&storageAction{base}).Handle(context.TODO(), atlasMap))
+		assert.NoError(t, (&deploymentAction{base}).Handle(context.TODO(), atlasMap))
+	}
+
+	// A ReadWriteOnce claim is created, and the pods using it are replaced rather than rolled
+	handle()
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "atlasmap-data", Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, base.client, claim))
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, claim.Spec.AccessModes)
+	assert.True(t, size.Equal(claim.Spec.Resources.Requests[corev1.ResourceStorage]))
+	assert.Equal(t, reasonClaimPending, storageReason(atlasMap))
+	deployment := getDeployment(t, base.client, atlasMap)
+	assert.Equal(t, appsv1.RecreateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+	podSpec := deployment.Spec.Template.Spec
+	if assert.Len(t, podSpec.Volumes, 1) {
+		assert.Equal(t, "atlasmap-data", podSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+		assert.Equal(t, workspaceMountPath, podSpec.Containers[0].VolumeMounts[0].MountPath)
+	}
+
+	// The claim is expanded once bound
+	claim.Status.Phase = corev1.ClaimBound
+	assert.NoError(t, base.client.Status().Update(context.TODO(), claim))
+	atlasMap.Spec.Storage.Size = &larger
+	handle()
+	claim = &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "atlasmap-data", Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, base.client, claim))
+	assert.True(t, larger.Equal(claim.Spec.Resources.Requests[corev1.ResourceStorage]))
+	assert.Equal(t, reasonClaimBound, storageReason(atlasMap))
+
+	// More than one replica requires a ReadWriteMany claim
+	atlasMap.Spec.Autoscaling = &v1alpha1.AtlasMapAutoscalingSpec{MaxReplicas: 3}
+	handle()
+	assert.Equal(t, reasonReadWriteManyRequired, storageReason(atlasMap))
+	atlasMap.Spec.Autoscaling = nil
+
+	// The claim is kept when the storage is removed, but no longer mounted
+	atlasMap.Spec.Storage = nil
+	handle()
+	assert.True(t, exists(t, base.client, &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "atlasmap-data", Namespace: atlasMap.Namespace}}))
+	assert.Empty(t, storageReason(atlasMap))
+	assert.Empty(t, getDeployment(t, base.client, atlasMap).Spec.Template.Spec.Volumes)
+}
+
+func TestStorageExistingClaim(t *testing.T) {
+	rwx := []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Storage = &v1alpha1.AtlasMapStorageSpec{ClaimName: "missing"}
+	base := newTestBaseAction(t, capabilities.Capabilities{}, &corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{Name: "shared", Namespace: atlasMap.Namespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: rwx},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	})
+	action := &storageAction{base}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Equal(t, reasonClaimNotFound, storageReason(atlasMap))
+
+	// Existing claims are used as they are
+	atlasMap.Spec.Storage.ClaimName = "shared"
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	assert.Equal(t, reasonClaimBound, storageReason(atlasMap))
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "shared", Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, base.client, claim))
+	assert.Empty(t, claim.OwnerReferences)
+	assert.Empty(t, claim.Labels)
+	assert.False(t, exists(t, base.client, &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "atlasmap-data", Namespace: atlasMap.Namespace}}))
+}
+
+func TestStorageNotControlled(t *testing.T) {
+	size, larger := resource.MustParse("1Gi"), resource.MustParse("2Gi")
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Storage = &v1alpha1.AtlasMapStorageSpec{Size: &larger}
+	action := &storageAction{newTestBaseAction(t, capabilities.Capabilities{}, &corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{Name: "atlasmap-data", Namespace: atlasMap.Namespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: size}}},
+	})}
+
+	assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "atlasmap-data", Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, action.client, claim))
+	assert.True(t, size.Equal(claim.Spec.Resources.Requests[corev1.ResourceStorage]))
+	assert.Empty(t, claim.OwnerReferences)
+	assert.Empty(t, claim.Labels)
+	condition := meta.FindStatusCondition(atlasMap.Status.Conditions, v1alpha1.AtlasMapConditionStorageReady)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, v1.ConditionFalse, condition.Status)
+		assert.Equal(t, reasonResourceConflict, condition.Reason)
+	}
+}
@@ -0,0 +1,196 @@
+package action
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/go-logr/logr"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, routev1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// newTestBaseAction returns an action base for a cluster with the given capabilities, backed by a fake client
+// holding the given objects
+func newTestBaseAction(t *testing.T, clusterCapabilities capabilities.Capabilities, objects ...client.Object) baseAction {
+	scheme := newTestScheme(t)
+	return baseAction{
+		log:          logr.Discard(),
+		client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		scheme:       scheme,
+		recorder:     record.NewFakeRecorder(100),
+		capabilities: capabilities.NewStaticDetector(clusterCapabilities),
+		name:         "Test",
+	}
+}
+
+func newTestAtlasMap() *v1alpha1.AtlasMap {
+	replicas := int32(1)
+	return &v1alpha1.AtlasMap{
+		TypeMeta:   v1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "AtlasMap"},
+		ObjectMeta: v1.ObjectMeta{Name: "atlasmap", Namespace: "test", UID: "atlasmap-uid", Generation: 1},
+		Spec:       v1alpha1.AtlasMapSpec{Replicas: &replicas, Version: "2.3.0"},
+	}
+}
+
+// controlledBy makes the AtlasMap the controller of the object
+func controlledBy(t *testing.T, atlasMap *v1alpha1.AtlasMap, object client.Object) client.Object {
+	assert.NoError(t, controllerutil.SetControllerReference(atlasMap, object, newTestScheme(t)))
+	return object
+}
+
+func exists(t *testing.T, c client.Client, object client.Object) bool {
+	err := c.Get(context.TODO(), client.ObjectKeyFromObject(object), object)
+	if errors.IsNotFound(err) {
+		return false
+	}
+	assert.NoError(t, err)
+	return true
+}
+
+func TestActionsOnlyChangeStatusInMemory(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	base := newTestBaseAction(t, capabilities.Capabilities{}, atlasMap.DeepCopy())
+
+	// The Deployment is created, and then reconciled
+	assert.NoError(t, (&deploymentAction{base}).Handle(context.TODO(), atlasMap))
+	assert.NoError(t, (&deploymentAction{base}).Handle(context.TODO(), atlasMap))
+	assert.Equal(t, v1alpha1.AtlasMapPhasePhaseDeploying, atlasMap.Status.Phase)
+	assert.Equal(t, "2.3.0", atlasMap.Status.Version)
+	assert.NotEmpty(t, atlasMap.Status.Image)
+
+	// The status is written by the controller once every action ran
+	stored := &v1alpha1.AtlasMap{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, base.client, stored))
+	assert.Equal(t, v1alpha1.AtlasMapStatus{}, stored.Status)
+}
+
+// failingUpdateClient rejects all updates
+type failingUpdateClient struct {
+	client.Client
+}
+
+func (c failingUpdateClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return errors.NewBadRequest("update rejected")
+}
+
+// events returns the "<type> <reason>" of the events recorded by the action
+func events(action baseAction) []string {
+	recorder := action.recorder.(*record.FakeRecorder)
+	var recorded []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			fields := strings.SplitN(event, " ", 3)
+			recorded = append(recorded, fields[0]+" "+fields[1])
+		default:
+			return recorded
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	base := newTestBaseAction(t, capabilities.Capabilities{})
+
+	assert.NoError(t, (&deploymentAction{base}).Handle(context.TODO(), atlasMap))
+	assert.Equal(t, []string{"Normal Created"}, events(base))
+	assert.NoError(t, (&deploymentAction{base}).Handle(context.TODO(), atlasMap))
+	assert.Equal(t, []string{"Normal PhaseChanged"}, events(base))
+
+	// Conflicts are not reported, as the AtlasMap is reconciled again
+	deployment := &appsv1.Deployment{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	assert.True(t, exists(t, base.client, deployment))
+	deployment.ResourceVersion = "999"
+	assert.Error(t, base.updateResource(context.TODO(), atlasMap, deployment))
+	assert.Empty(t, events(base))
+
+	failing := base
+	failing.client = failingUpdateClient{base.client}
+	assert.True(t, exists(t, base.client, deployment))
+	assert.Error(t, failing.updateResource(context.TODO(), atlasMap, deployment))
+	assert.Equal(t, []string{"Warning UpdateFailed"}, events(base))
+
+	assert.NoError(t, base.removeResource(context.TODO(), atlasMap, deployment))
+	assert.Equal(t, []string{"Normal Deleted"}, events(base))
+}
+
+func TestResourceConflictEvent(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	base := newTestBaseAction(t, capabilities.Capabilities{}, &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}})
+
+	assert.NoError(t, (&serviceAction{base}).Handle(context.TODO(), atlasMap))
+	assert.Equal(t, []string{"Warning ResourceConflict"}, events(base))
+
+	// Creating a resource that already exists is not reported either
+	assert.Error(t, base.deployResource(context.TODO(), atlasMap, createAtlasMapService(atlasMap, v1alpha1.AtlasMapExposureIngress, false)))
+	assert.Empty(t, events(base))
+}
+
+func TestExposureSwitching(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	base := newTestBaseAction(t, capabilities.Capabilities{Routes: true, IngressAPIVersion: netv1.SchemeGroupVersion.String()})
+	route := &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	ingress := &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	service := &corev1.Service{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	expose := func(exposure v1alpha1.AtlasMapExposureType) {
+		atlasMap.Spec.Exposure = &v1alpha1.AtlasMapExposureSpec{Type: exposure}
+		for _, action := range []Action{&serviceAction{base}, &routeAction{base}, &ingressAction{base}} {
+			assert.NoError(t, action.Handle(context.TODO(), atlasMap))
+		}
+		assert.True(t, exists(t, base.client, service))
+	}
+
+	// OpenShift defaults to a Route
+	expose("")
+	assert.True(t, exists(t, base.client, route))
+	assert.False(t, exists(t, base.client, ingress))
+
+	expose(v1alpha1.AtlasMapExposureIngress)
+	assert.False(t, exists(t, base.client, route))
+	assert.True(t, exists(t, base.client, ingress))
+
+	expose(v1alpha1.AtlasMapExposureLoadBalancer)
+	assert.False(t, exists(t, base.client, ingress))
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, service.Spec.Type)
+
+	expose(v1alpha1.AtlasMapExposureNone)
+	assert.False(t, exists(t, base.client, route))
+	assert.False(t, exists(t, base.client, ingress))
+	assert.Equal(t, corev1.ServiceTypeClusterIP, service.Spec.Type)
+	assert.Equal(t, "http://atlasmap.test.svc:8585", atlasMap.Status.URL)
+}
+
+func TestRemoveResourceSkipsUncontrolledResources(t *testing.T) {
+	atlasMap := newTestAtlasMap()
+	atlasMap.Spec.Exposure = &v1alpha1.AtlasMapExposureSpec{Type: v1alpha1.AtlasMapExposureNone}
+	ingress := &netv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}}
+	route := controlledBy(t, atlasMap, &routev1.Route{ObjectMeta: v1.ObjectMeta{Name: atlasMap.Name, Namespace: atlasMap.Namespace}})
+	base := newTestBaseAction(t, capabilities.Capabilities{Routes: true, IngressAPIVersion: netv1.SchemeGroupVersion.String()}, ingress, route)
+
+	assert.NoError(t, (&ingressAction{base}).Handle(context.TODO(), atlasMap))
+	assert.NoError(t, (&routeAction{base}).Handle(context.TODO(), atlasMap))
+	assert.True(t, exists(t, base.client, ingress))
+	assert.False(t, exists(t, base.client, route))
+}
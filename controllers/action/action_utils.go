@@ -1,34 +1,154 @@
 package action
 
 import (
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/catalog"
 	"github.com/atlasmap/atlasmap-operator/controllers/config"
 	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-const (
-	springBoot1ProbeEndpointPath = "/management/health"
-	springBoot2ProbeEndpointPath = "/actuator/health"
-)
+const atlasMapAppName = "atlasmap"
 
-func atlasMapLabels(atlasMap *v1alpha1.AtlasMap) map[string]string {
+// PodSelector selects the pods of every AtlasMap
+var PodSelector = labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": atlasMapAppName})
+
+// atlasMapSelectorLabels are the labels selecting the pods of an AtlasMap. Selectors are immutable
+// so they must not depend on anything that changes during the lifetime of the AtlasMap.
+func atlasMapSelectorLabels(atlasMap *v1alpha1.AtlasMap) map[string]string {
 	return map[string]string{
-		"atlasmap.io/name":             atlasMap.ObjectMeta.Name,
-		"atlasmap.io/version":          atlasMapVersion(atlasMap),
-		"atlasmap.io/operator.version": config.DefaultOperatorVersion,
+		"app.kubernetes.io/name":     atlasMapAppName,
+		"app.kubernetes.io/instance": atlasMap.ObjectMeta.Name,
+	}
+}
+
+// atlasMapPodLabels leave out the operator version so that operator upgrades do not restart the pods
+func atlasMapPodLabels(atlasMap *v1alpha1.AtlasMap) map[string]string {
+	labels := atlasMapSelectorLabels(atlasMap)
+	labels["atlasmap.io/name"] = atlasMap.ObjectMeta.Name
+	labels["atlasmap.io/version"] = atlasMapVersion(atlasMap)
+	return labels
+}
+
+func atlasMapLabels(atlasMap *v1alpha1.AtlasMap) map[string]string {
+	labels := atlasMapPodLabels(atlasMap)
+	labels["app.kubernetes.io/managed-by"] = "atlasmap-operator"
+	labels["atlasmap.io/operator.version"] = config.DefaultOperatorVersion
+	return labels
+}
+
+// mergeLabels adds the given labels to the object and reports whether its labels changed
+func mergeLabels(object v1.Object, labels map[string]string) bool {
+	current := object.GetLabels()
+	if current == nil {
+		current = make(map[string]string, len(labels))
+	}
+	changed := false
+	for name, value := range labels {
+		if existing, ok := current[name]; !ok || existing != value {
+			current[name] = value
+			changed = true
+		}
 	}
+	object.SetLabels(current)
+	return changed
+}
+
+// managedAnnotationsAnnotation records the keys of the annotations that the operator set on a resource, so that
+// annotations removed from the AtlasMap are removed from the resource as well
+const managedAnnotationsAnnotation = "atlasmap.io/managed-annotations"
+
+// reconcileAnnotations sets the desired annotations on the object, removes the annotations that the operator set
+// before and that are no longer desired, and reports whether its annotations changed. Annotations set by other
+// controllers are kept.
+func reconcileAnnotations(object v1.Object, desired map[string]string) bool {
+	current := object.GetAnnotations()
+	if current == nil {
+		current = make(map[string]string, len(desired)+1)
+	}
+	changed := false
+	for _, name := range strings.Split(current[managedAnnotationsAnnotation], ",") {
+		if _, ok := desired[name]; ok || len(name) == 0 {
+			continue
+		}
+		if _, ok := current[name]; ok {
+			delete(current, name)
+			changed = true
+		}
+	}
+	for name, value := range desired {
+		if existing, ok := current[name]; !ok || existing != value {
+			current[name] = value
+			changed = true
+		}
+	}
+
+	managed := strings.Join(sortedKeys(desired), ",")
+	if existing, ok := current[managedAnnotationsAnnotation]; len(managed) == 0 && ok {
+		delete(current, managedAnnotationsAnnotation)
+		changed = true
+	} else if len(managed) > 0 && existing != managed {
+		current[managedAnnotationsAnnotation] = managed
+		changed = true
+	}
+	if len(current) == 0 {
+		current = nil
+	}
+	object.SetAnnotations(current)
+	return changed
 }
 
 func atlasMapImage(atlasMap *v1alpha1.AtlasMap) string {
-	if len(atlasMap.Spec.Version) == 0 {
+	if len(atlasMap.Spec.Image) > 0 {
+		return atlasMap.Spec.Image
+	}
+	// The default version may be pinned to an image digest by the operator configuration
+	if version := atlasMap.Spec.Version; len(version) == 0 || version == config.DefaultConfiguration.Version {
 		return config.DefaultConfiguration.GetAtlasMapImage()
 	}
 	return util.ImageName(config.DefaultConfiguration.AtlasMapImage, atlasMap.Spec.Version)
 }
 
+func atlasMapReplicas(atlasMap *v1alpha1.AtlasMap) *int32 {
+	replicas := v1alpha1.DefaultReplicas
+	if atlasMap.Spec.Replicas != nil {
+		replicas = *atlasMap.Spec.Replicas
+	}
+	if autoscaling := atlasMap.Spec.Autoscaling; autoscaling != nil {
+		// Start from the lower bound and leave the rest to the autoscaler
+		replicas = 1
+		if autoscaling.MinReplicas != nil {
+			replicas = *autoscaling.MinReplicas
+		}
+	}
+	return &replicas
+}
+
+// atlasMapMaxReplicas is the largest number of AtlasMap pods that may run
+func atlasMapMaxReplicas(atlasMap *v1alpha1.AtlasMap) int32 {
+	if autoscaling := atlasMap.Spec.Autoscaling; autoscaling != nil {
+		return autoscaling.MaxReplicas
+	}
+	return *atlasMapReplicas(atlasMap)
+}
+
+func atlasMapImagePullPolicy(atlasMap *v1alpha1.AtlasMap, image string) corev1.PullPolicy {
+	if len(atlasMap.Spec.ImagePullPolicy) > 0 {
+		return atlasMap.Spec.ImagePullPolicy
+	}
+	// An image pinned to a digest never changes so there is no need to pull it again
+	if _, _, digest := util.ParseImage(image); len(digest) > 0 {
+		return corev1.PullIfNotPresent
+	}
+	return corev1.PullAlways
+}
+
 func atlasMapVersion(atlasMap *v1alpha1.AtlasMap) string {
 	if len(atlasMap.Spec.Version) == 0 {
 		return config.DefaultConfiguration.Version
@@ -36,25 +156,99 @@ func atlasMapVersion(atlasMap *v1alpha1.AtlasMap) string {
 	return atlasMap.Spec.Version
 }
 
-func atlasMapProbePath(atlasMap *v1alpha1.AtlasMap) (string, error) {
-	// Handle differences in Spring Boot actuator health endpoint path
-	if atlasMap.Spec.Version != "" {
-		versionParts := strings.Split(atlasMap.Spec.Version, ".")
-		if len(versionParts) > 1 {
-			major, err := strconv.Atoi(versionParts[0])
-			if err != nil {
-				return "", err
-			}
+// atlasMapCatalogEntry resolves the version catalog entry of the AtlasMap. The operator default
+// version may be any tag of its configured image, in which case the latest entry applies.
+func atlasMapCatalogEntry(atlasMap *v1alpha1.AtlasMap) (*catalog.Entry, error) {
+	version := atlasMapVersion(atlasMap)
+	entry, err := catalog.Lookup(version)
+	if err != nil && version == config.DefaultConfiguration.Version {
+		return catalog.Latest(), nil
+	}
+	return entry, err
+}
 
-			minor, err := strconv.Atoi(versionParts[1])
-			if err != nil {
-				return "", err
-			}
+// setEnvVar sets, or removes when the value is empty, an environment variable of the container
+// and reports whether the container changed
+func setEnvVar(container *corev1.Container, name string, value string) bool {
+	for i, env := range container.Env {
+		if env.Name != name {
+			continue
+		}
+		if len(value) == 0 {
+			container.Env = append(container.Env[:i], container.Env[i+1:]...)
+			return true
+		}
+		if env.Value == value && env.ValueFrom == nil {
+			return false
+		}
+		container.Env[i] = corev1.EnvVar{Name: name, Value: value}
+		return true
+	}
+	if len(value) == 0 {
+		return false
+	}
+	container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+	return true
+}
 
-			if major == 1 && minor < 43 {
-				return springBoot1ProbeEndpointPath, nil
-			}
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setVolume sets, or removes when the volume is nil, the volume with the given name of the pod
+// and reports whether the pod changed
+func setVolume(podSpec *corev1.PodSpec, name string, volume *corev1.Volume) bool {
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name != name {
+			continue
+		}
+		if volume == nil {
+			podSpec.Volumes = append(podSpec.Volumes[:i], podSpec.Volumes[i+1:]...)
+			return true
 		}
+		if equality.Semantic.DeepDerivative(*volume, podSpec.Volumes[i]) {
+			return false
+		}
+		podSpec.Volumes[i] = *volume
+		return true
+	}
+	if volume == nil {
+		return false
 	}
-	return springBoot2ProbeEndpointPath, nil
+	podSpec.Volumes = append(podSpec.Volumes, *volume)
+	return true
+}
+
+// setVolumeMount sets, or removes when the mount is nil, the volume mount with the given name of the container
+// and reports whether the container changed
+func setVolumeMount(container *corev1.Container, name string, mount *corev1.VolumeMount) bool {
+	for i := range container.VolumeMounts {
+		if container.VolumeMounts[i].Name != name {
+			continue
+		}
+		if mount == nil {
+			container.VolumeMounts = append(container.VolumeMounts[:i], container.VolumeMounts[i+1:]...)
+			return true
+		}
+		if equality.Semantic.DeepEqual(*mount, container.VolumeMounts[i]) {
+			return false
+		}
+		container.VolumeMounts[i] = *mount
+		return true
+	}
+	if mount == nil {
+		return false
+	}
+	container.VolumeMounts = append(container.VolumeMounts, *mount)
+	return true
+}
+
+// atlasMapServingCertSecretName is the name of the Secret in which OpenShift stores the serving certificate of the AtlasMap Service
+func atlasMapServingCertSecretName(atlasMap *v1alpha1.AtlasMap) string {
+	return atlasMap.Name + "-tls"
 }
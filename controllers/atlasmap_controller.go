@@ -20,32 +20,58 @@ import (
 	"context"
 	"fmt"
 	gort "runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	consolev1 "github.com/openshift/api/console/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
 	"github.com/atlasmap/atlasmap-operator/controllers/action"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
 	"github.com/atlasmap/atlasmap-operator/controllers/config"
-	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	"github.com/atlasmap/atlasmap-operator/controllers/metrics"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// atlasMapFinalizer guards the deletion of AtlasMaps until their cluster-scoped resources are removed
+const atlasMapFinalizer = "atlasmap.io/finalizer"
+
+// Field indexes of the AtlasMaps by the names of the ConfigMaps and Secrets they reference
+const (
+	configMapIndex = "spec.configMapRefs"
+	secretIndex    = "spec.secretRefs"
+)
+
 // AtlasMapReconciler reconciles a AtlasMap object
 type AtlasMapReconciler struct {
-	Client client.Client
-	Scheme *runtime.Scheme
-	config *rest.Config
+	Client       client.Client
+	Scheme       *runtime.Scheme
+	Capabilities *capabilities.Detector
+
+	controller controller.Controller
+	watchLock  sync.Mutex
+	watched    map[schema.GroupVersionKind]bool
 }
 
 var actions []action.Action
@@ -70,90 +96,301 @@ func (r *AtlasMapReconciler) Reconcile(ctx context.Context, request ctrl.Request
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling AtlasMap")
 
+	ctx, writes := metrics.WithWriteCounter(ctx)
+	defer func() {
+		metrics.ReconcileWrites.Observe(float64(atomic.LoadInt64(writes)))
+	}()
+
 	// Fetch the AtlasMap instance
 	instance := &v1alpha1.AtlasMap{}
 	err := r.Client.Get(ctx, request.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Owned objects are automatically garbage collected and cluster-scoped objects are removed by the finalizer.
 			// Return and don't requeue
-			instance.ObjectMeta = metav1.ObjectMeta{
-				Name:      request.Name,
-				Namespace: request.Namespace,
-			}
-
-			isOpenShift, _ := util.IsOpenShift(r.config)
-			if isOpenShift && util.IsOpenShift43Plus(r.config) {
-				//Handle removal of cluster-scope object.
-				return r.removeConsoleLink(instance)
-			}
-
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	if instance.DeletionTimestamp != nil {
+		return r.finalize(ctx, instance)
+	}
+
+	// Until the capabilities are detected, the actions could remove the resources of the optional APIs of the cluster
+	if !r.Capabilities.Detected() {
+		reqLogger.Info("Waiting for the cluster capabilities to be detected")
+		return reconcile.Result{RequeueAfter: capabilities.RetryInterval}, nil
+	}
+
+	if r.Capabilities.Get().ConsoleLinks && !controllerutil.ContainsFinalizer(instance, atlasMapFinalizer) {
+		controllerutil.AddFinalizer(instance, atlasMapFinalizer)
+		if err := r.Client.Update(ctx, instance); err != nil {
+			if errors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, err
+		}
+	}
+
+	status := instance.Status.DeepCopy()
 	for _, a := range actions {
 		reqLogger.Info("Running action: " + a.GetName())
-		if err := a.Handle(ctx, instance); err != nil {
+		start := time.Now()
+		err := a.Handle(ctx, instance)
+		metrics.ObserveAction(a.GetName(), start, err)
+		if err != nil {
 			if errors.IsConflict(err) {
 				return reconcile.Result{Requeue: true}, nil
 			}
 			reqLogger.Error(err, "Error running action: "+a.GetName())
+			if statusErr := r.updateStatus(ctx, instance, status, a.GetName(), err); statusErr != nil && !errors.IsConflict(statusErr) {
+				reqLogger.Error(statusErr, "Error updating AtlasMap status")
+			}
 			return reconcile.Result{}, err
 		}
 	}
 
+	if err := r.updateStatus(ctx, instance, status, "", nil); err != nil {
+		if errors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
 	return reconcile.Result{}, nil
 }
 
+// finalize removes the cluster-scoped resources of a deleted AtlasMap, which cannot be garbage collected
+// through owner references, and then releases the AtlasMap
+func (r *AtlasMapReconciler) finalize(ctx context.Context, atlasMap *v1alpha1.AtlasMap) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(atlasMap, atlasMapFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	if err := action.RemoveConsoleLink(ctx, r.Client, atlasMap); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(atlasMap, atlasMapFinalizer)
+	if err := r.Client.Update(ctx, atlasMap); err != nil {
+		if errors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// updateStatus summarises the conditions set by the actions into the Ready condition and, when every
+// action succeeded, records the generation that has been reconciled
+func (r *AtlasMapReconciler) updateStatus(ctx context.Context, atlasMap *v1alpha1.AtlasMap, previous *v1alpha1.AtlasMapStatus, failedAction string, reconcileErr error) error {
+	if reconcileErr != nil {
+		reason := failedAction + "ReconcileFailed"
+		action.SetCondition(atlasMap, v1alpha1.AtlasMapConditionDegraded, metav1.ConditionTrue, reason, reconcileErr.Error())
+		action.SetCondition(atlasMap, v1alpha1.AtlasMapConditionReady, metav1.ConditionFalse, reason, reconcileErr.Error())
+	} else {
+		atlasMap.Status.ObservedGeneration = atlasMap.Generation
+		status, reason, message := readiness(atlasMap)
+		action.SetCondition(atlasMap, v1alpha1.AtlasMapConditionReady, status, reason, message)
+	}
+
+	if equality.Semantic.DeepEqual(previous, &atlasMap.Status) {
+		return nil
+	}
+	return r.Client.Status().Update(ctx, atlasMap)
+}
+
+func readiness(atlasMap *v1alpha1.AtlasMap) (metav1.ConditionStatus, string, string) {
+	conditions := atlasMap.Status.Conditions
+	if degraded := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionDegraded); degraded != nil && degraded.Status == metav1.ConditionTrue {
+		return metav1.ConditionFalse, degraded.Reason, degraded.Message
+	}
+	if version := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionVersionSupported); version != nil && version.Status == metav1.ConditionFalse {
+		return metav1.ConditionFalse, version.Reason, version.Message
+	}
+	if available := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionAvailable); available == nil || available.Status != metav1.ConditionTrue {
+		if available == nil {
+			return metav1.ConditionFalse, "NotAvailable", "AtlasMap is not available"
+		}
+		return metav1.ConditionFalse, available.Reason, available.Message
+	}
+	if autoscaling := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionAutoscalingReady); autoscaling != nil && autoscaling.Status != metav1.ConditionTrue {
+		return metav1.ConditionFalse, autoscaling.Reason, autoscaling.Message
+	}
+	if storage := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionStorageReady); storage != nil && storage.Status != metav1.ConditionTrue {
+		return metav1.ConditionFalse, storage.Reason, storage.Message
+	}
+	if exposure := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionExposureReady); exposure != nil && exposure.Status != metav1.ConditionTrue {
+		return metav1.ConditionFalse, exposure.Reason, exposure.Message
+	}
+	if config := meta.FindStatusCondition(conditions, v1alpha1.AtlasMapConditionConfigReady); config != nil && config.Status != metav1.ConditionTrue {
+		return metav1.ConditionFalse, config.Reason, config.Message
+	}
+	return metav1.ConditionTrue, "AtlasMapReady", "AtlasMap is available"
+}
+
+// atlasMapsReferencing maps a ConfigMap or Secret to the AtlasMaps of its namespace that reference it through the given index
+func (r *AtlasMapReconciler) atlasMapsReferencing(index string) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		atlasMaps := &v1alpha1.AtlasMapList{}
+		if err := r.Client.List(context.Background(), atlasMaps, client.InNamespace(object.GetNamespace()), client.MatchingFields{index: object.GetName()}); err != nil {
+			log.Error(err, "Error listing AtlasMaps referencing "+object.GetName(), "index", index)
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(atlasMaps.Items))
+		for _, atlasMap := range atlasMaps.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: atlasMap.Name, Namespace: atlasMap.Namespace}})
+		}
+		return requests
+	}
+}
+
 func printVersion() {
 	log.Info(fmt.Sprintf("Go Version: %s", gort.Version()))
 	log.Info(fmt.Sprintf("Go OS/Arch: %s/%s", gort.GOOS, gort.GOARCH))
 	log.Info(fmt.Sprintf("AtlasMap Operator Version: %s", config.DefaultOperatorVersion))
 	log.Info(fmt.Sprintf("AtlasMap Operator Image: %s", config.DefaultOperatorImage))
+	log.Info(fmt.Sprintf("AtlasMap Default Image: %s", config.DefaultConfiguration.GetAtlasMapImage()))
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AtlasMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	printVersion()
 
+	if err := metrics.RegisterFleetCollector(mgr.GetClient()); err != nil {
+		return err
+	}
+
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &v1alpha1.AtlasMap{}, configMapIndex, func(object client.Object) []string {
+		return action.ReferencedConfigMaps(object.(*v1alpha1.AtlasMap))
+	}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &v1alpha1.AtlasMap{}, secretIndex, func(object client.Object) []string {
+		return action.ReferencedSecrets(object.(*v1alpha1.AtlasMap))
+	}); err != nil {
+		return err
+	}
+
 	// Create a new controller
 	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.AtlasMap{}).
-		Owns(&appsv1.Deployment{})
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		// Only the metadata of ConfigMaps and Secrets is cached, the referenced ones are read from the API server.
+		// The ConfigMap holding the application properties is referenced by its AtlasMap, and the metadata informers
+		// are shared with the AtlasMapping controller
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.atlasMapsReferencing(configMapIndex)), ctrlbuilder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.atlasMapsReferencing(secretIndex)), ctrlbuilder.OnlyMetadata)
+
+	if err := mgr.Add(newConsoleLinkSweeper(mgr.GetClient(), mgr.GetAPIReader(), r.Capabilities, consoleLinkSweepInterval)); err != nil {
+		return err
+	}
+
+	actions = action.NewOperatorActions(log, mgr, r.Capabilities)
 
-	isOpenShift, err := util.IsOpenShift(mgr.GetConfig())
+	c, err := builder.Build(r)
 	if err != nil {
 		return err
 	}
+	r.controller = c
 
-	if isOpenShift {
-		builder.Owns(&routev1.Route{})
-	} else {
-		builder.Owns(&netv1.Ingress{})
+	// The resources of the optional APIs are watched once the cluster is found to serve them
+	r.Capabilities.OnChange(func(clusterCapabilities capabilities.Capabilities) {
+		if err := r.watchOptionalAPIs(clusterCapabilities); err != nil {
+			log.Error(err, "Error watching the optional APIs of the cluster")
+		}
+	})
+	if r.Capabilities.Detected() {
+		return r.watchOptionalAPIs(r.Capabilities.Get())
 	}
+	return nil
+}
 
-	actions = action.NewOperatorActions(log, mgr)
+// watchOptionalAPIs watches the resources owned by AtlasMaps for each optional API served by the cluster,
+// which has not been watched yet
+func (r *AtlasMapReconciler) watchOptionalAPIs(clusterCapabilities capabilities.Capabilities) error {
+	r.watchLock.Lock()
+	defer r.watchLock.Unlock()
+	if r.watched == nil {
+		r.watched = make(map[schema.GroupVersionKind]bool)
+	}
 
-	return builder.Complete(r)
-}
+	var owned []client.Object
+	if clusterCapabilities.IngressAPIVersion == netv1.SchemeGroupVersion.String() {
+		owned = append(owned, &netv1.Ingress{TypeMeta: metav1.TypeMeta{APIVersion: netv1.SchemeGroupVersion.String(), Kind: "Ingress"}})
+	}
+	if clusterCapabilities.Routes {
+		owned = append(owned, &routev1.Route{TypeMeta: metav1.TypeMeta{APIVersion: routev1.GroupVersion.String(), Kind: "Route"}})
+	}
+	if len(clusterCapabilities.GatewayAPIVersion) > 0 {
+		owned = append(owned, unstructuredObject(clusterCapabilities.GatewayAPIVersion, "HTTPRoute"))
+	}
+	if len(clusterCapabilities.GatewayAPIVersion) > 0 {
+		// The URL and readiness of the AtlasMap depend on the listeners and status of its Gateway
+		gateway := unstructuredObject(clusterCapabilities.GatewayAPIVersion, "Gateway")
+		if err := r.watch(gateway, handler.EnqueueRequestsFromMapFunc(r.atlasMapsUsingGateway)); err != nil {
+			return err
+		}
+	}
+	if clusterCapabilities.Monitoring {
+		owned = append(owned, unstructuredObject(action.MonitoringAPIVersion, "ServiceMonitor"), unstructuredObject(action.MonitoringAPIVersion, "PrometheusRule"))
+	}
 
-func (r *AtlasMapReconciler) removeConsoleLink(atlasMap *v1alpha1.AtlasMap) (request reconcile.Result, err error) {
-	consoleLinkName := util.ConsoleLinkName(atlasMap)
-	consoleLink := &consolev1.ConsoleLink{}
-	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: consoleLinkName}, consoleLink)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return reconcile.Result{}, err
+	for _, object := range owned {
+		if err := r.watchOwned(object); err != nil {
+			return err
 		}
-	} else {
-		err = r.Client.Delete(context.TODO(), consoleLink)
-		if err != nil {
-			return reconcile.Result{}, err
+	}
+
+	// Only spec changes of the HorizontalPodAutoscaler are reverted, its status changes constantly
+	hpa := unstructuredObject(clusterCapabilities.AutoscalerAPIVersion, "HorizontalPodAutoscaler")
+	return r.watchOwned(hpa, predicate.GenerationChangedPredicate{})
+}
+
+func (r *AtlasMapReconciler) watchOwned(object client.Object, predicates ...predicate.Predicate) error {
+	return r.watch(object, &handler.EnqueueRequestForOwner{OwnerType: &v1alpha1.AtlasMap{}, IsController: true}, predicates...)
+}
+
+func (r *AtlasMapReconciler) watch(object client.Object, eventHandler handler.EventHandler, predicates ...predicate.Predicate) error {
+	gvk := object.GetObjectKind().GroupVersionKind()
+	if r.watched[gvk] {
+		return nil
+	}
+	if err := r.controller.Watch(&source.Kind{Type: object}, eventHandler, predicates...); err != nil {
+		return err
+	}
+	log.Info("Watching "+gvk.Kind, "apiVersion", gvk.GroupVersion().String())
+	r.watched[gvk] = true
+	return nil
+}
+
+// atlasMapsUsingGateway maps a Gateway to the AtlasMaps exposed through an HTTPRoute attached to it, in any namespace.
+// Gateways change rarely, so the AtlasMaps are filtered rather than indexed.
+func (r *AtlasMapReconciler) atlasMapsUsingGateway(object client.Object) []reconcile.Request {
+	atlasMaps := &v1alpha1.AtlasMapList{}
+	if err := r.Client.List(context.Background(), atlasMaps); err != nil {
+		log.Error(err, "Error listing AtlasMaps using Gateway "+object.GetName(), "namespace", object.GetNamespace())
+		return nil
+	}
+	gateway := object.GetNamespace() + "/" + object.GetName()
+	var requests []reconcile.Request
+	for i := range atlasMaps.Items {
+		if action.ReferencedGateway(&atlasMaps.Items[i]) == gateway {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&atlasMaps.Items[i])})
 		}
 	}
-	return reconcile.Result{}, nil
+	return requests
+}
+
+func unstructuredObject(apiVersion string, kind string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(apiVersion)
+	object.SetKind(kind)
+	return object
 }
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	consolev1 "github.com/openshift/api/console/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestClient returns a fake client holding the given AtlasMaps and ConsoleLinks
+func newTestClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, consolev1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func TestFinalizeRemovesConsoleLink(t *testing.T) {
+	now := metav1.Now()
+	atlasMap := &v1alpha1.AtlasMap{ObjectMeta: metav1.ObjectMeta{
+		Name:              "atlasmap",
+		Namespace:         "test",
+		DeletionTimestamp: &now,
+		Finalizers:        []string{atlasMapFinalizer},
+	}}
+	link := newTestConsoleLink(util.ConsoleLinkName(atlasMap), map[string]string{
+		util.ConsoleLinkNamespaceLabel: atlasMap.Namespace,
+		util.ConsoleLinkNameLabel:      atlasMap.Name,
+	})
+	c := newTestClient(t, atlasMap, link)
+	r := &AtlasMapReconciler{Client: c, Capabilities: capabilities.NewStaticDetector(capabilities.Capabilities{ConsoleLinks: true})}
+
+	_, err := r.finalize(context.TODO(), atlasMap)
+	assert.NoError(t, err)
+	assert.True(t, errors.IsNotFound(c.Get(context.TODO(), client.ObjectKeyFromObject(link), &consolev1.ConsoleLink{})))
+
+	// Releasing the finalizer completes the deletion of the AtlasMap
+	assert.False(t, controllerutil.ContainsFinalizer(atlasMap, atlasMapFinalizer))
+	assert.True(t, errors.IsNotFound(c.Get(context.TODO(), client.ObjectKeyFromObject(atlasMap), &v1alpha1.AtlasMap{})))
+}
+
+func TestAtlasMapsUsingGateway(t *testing.T) {
+	newAtlasMap := func(namespace string, name string, exposure *v1alpha1.AtlasMapExposureSpec) *v1alpha1.AtlasMap {
+		return &v1alpha1.AtlasMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Spec: v1alpha1.AtlasMapSpec{Exposure: exposure}}
+	}
+	gatewayRef := &v1alpha1.AtlasMapExposureSpec{
+		Type:    v1alpha1.AtlasMapExposureGatewayHTTPRoute,
+		Gateway: &v1alpha1.AtlasMapGatewayReference{Name: "gateway", Namespace: "infra"},
+	}
+	r := &AtlasMapReconciler{Client: newTestClient(t,
+		newAtlasMap("a", "attached", gatewayRef),
+		newAtlasMap("infra", "local", &v1alpha1.AtlasMapExposureSpec{
+			Type:    v1alpha1.AtlasMapExposureGatewayHTTPRoute,
+			Gateway: &v1alpha1.AtlasMapGatewayReference{Name: "gateway"},
+		}),
+		newAtlasMap("a", "ingress", &v1alpha1.AtlasMapExposureSpec{Type: v1alpha1.AtlasMapExposureIngress}),
+	)}
+
+	gateway := &unstructured.Unstructured{}
+	gateway.SetName("gateway")
+	gateway.SetNamespace("infra")
+	requests := r.atlasMapsUsingGateway(gateway)
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "a", Name: "attached"}},
+		{NamespacedName: types.NamespacedName{Namespace: "infra", Name: "local"}},
+	}, requests)
+}
@@ -0,0 +1,373 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/mapping"
+)
+
+// Field index of the AtlasMappings by the name of their AtlasMap
+const atlasMapNameIndex = "spec.atlasMapName"
+
+// Reasons of the Synced condition and of the events recorded on AtlasMappings
+const (
+	reasonMappingSynced      = "Synced"
+	reasonMappingLoaded      = "Loaded"
+	reasonInvalidSource      = "InvalidSource"
+	reasonSourceNotFound     = "SourceNotFound"
+	reasonAtlasMapNotFound   = "AtlasMapNotFound"
+	reasonWaitingForAtlasMap = "WaitingForAtlasMap"
+	reasonLoading            = "Loading"
+	reasonSyncFailed         = "SyncFailed"
+)
+
+// jobDeletionRetryInterval is how long to wait for a loader Job being deleted before creating a new one
+const jobDeletionRetryInterval = 5 * time.Second
+
+// AtlasMappingReconciler loads AtlasMappings into the pods of their AtlasMap
+type AtlasMappingReconciler struct {
+	Client   client.Client
+	Scheme   *runtime.Scheme
+	Loader   *mapping.Loader
+	Recorder record.EventRecorder
+}
+
+// Note: No longer used for generating as ClusterRole and Binding resources edited manually
+//+kubebuilder:rbac:groups=atlasmap.io,resources=atlasmappings,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=atlasmap.io,resources=atlasmappings/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+// Reconcile loads the mapping of an AtlasMapping into every ready pod of its AtlasMap that has not loaded the
+// current content since the pod, or its AtlasMap container, started
+func (r *AtlasMappingReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling AtlasMapping")
+
+	atlasMapping := &v1alpha1.AtlasMapping{}
+	if err := r.Client.Get(ctx, request.NamespacedName, atlasMapping); err != nil {
+		if errors.IsNotFound(err) {
+			// The loader Job is garbage collected with the AtlasMapping
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	previous := atlasMapping.Status.DeepCopy()
+	result, err := r.sync(ctx, atlasMapping)
+	if err != nil {
+		reqLogger.Error(err, "Error syncing AtlasMapping")
+	}
+
+	if !equality.Semantic.DeepEqual(previous, &atlasMapping.Status) {
+		if statusErr := r.Client.Status().Update(ctx, atlasMapping); statusErr != nil {
+			if errors.IsConflict(statusErr) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, statusErr
+		}
+	}
+	return result, err
+}
+
+func (r *AtlasMappingReconciler) sync(ctx context.Context, atlasMapping *v1alpha1.AtlasMapping) (ctrl.Result, error) {
+	if err := mapping.ValidateSource(atlasMapping.Spec.Source); err != nil {
+		setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonInvalidSource, err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	atlasMap := &v1alpha1.AtlasMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: atlasMapping.Spec.AtlasMapName, Namespace: atlasMapping.Namespace}, atlasMap); err != nil {
+		if errors.IsNotFound(err) {
+			setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonAtlasMapNotFound,
+				fmt.Sprintf("AtlasMap %s not found", atlasMapping.Spec.AtlasMapName))
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	var content []byte
+	if atlasMapping.Spec.Source.PersistentVolumeClaim == nil {
+		var err error
+		content, err = mapping.ReadContent(ctx, r.Client, atlasMapping)
+		if err != nil {
+			var notFound *mapping.SourceNotFoundError
+			if goerrors.As(err, &notFound) {
+				setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonSourceNotFound, notFound.Message)
+				return reconcile.Result{}, nil
+			}
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Changing the content or how it is loaded requires loading it again into every pod
+	contentHash := mapping.ContentHash(atlasMapping, content)
+	if atlasMapping.Status.ContentHash != contentHash {
+		atlasMapping.Status.ContentHash = contentHash
+		atlasMapping.Status.Pods = nil
+	}
+
+	pods, err := r.atlasMapPods(ctx, atlasMap)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	atlasMapping.Status.Pods = mapping.CurrentPodStatuses(pods, atlasMapping.Status.Pods)
+
+	ready := mapping.ReadyPods(pods)
+	if len(ready) == 0 {
+		setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonWaitingForAtlasMap,
+			fmt.Sprintf("Waiting for a ready pod of AtlasMap %s", atlasMap.Name))
+		return reconcile.Result{}, nil
+	}
+
+	if atlasMapping.Spec.Source.PersistentVolumeClaim != nil {
+		return r.syncJob(ctx, atlasMapping, atlasMap, ready)
+	}
+
+	var failures []string
+	pending := mapping.PendingPods(ready, atlasMapping.Status.Pods)
+	for i := range pending {
+		pod := &pending[i]
+		if err := r.Loader.Load(ctx, mapping.PodTarget(pod, atlasMap), atlasMapping.MappingFormat(), atlasMapping.Spec.DefinitionID, content); err != nil {
+			failures = append(failures, fmt.Sprintf("pod %s: %v", pod.Name, err))
+			continue
+		}
+		r.podLoaded(atlasMapping, mapping.PodStatus(pod))
+	}
+
+	if len(failures) > 0 {
+		message := "Failed to load the mapping into " + strings.Join(failures, "; ")
+		r.Recorder.Event(atlasMapping, corev1.EventTypeWarning, reasonSyncFailed, message)
+		setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonSyncFailed, message)
+		return reconcile.Result{}, goerrors.New(message)
+	}
+
+	setMappingSynced(atlasMapping)
+	return reconcile.Result{}, nil
+}
+
+// syncJob loads a mapping stored on a PersistentVolumeClaim, which the operator cannot read, with a Job mounting
+// the claim. A single Job runs at a time, and its outcome is recorded before a Job for other pods is created
+func (r *AtlasMappingReconciler) syncJob(ctx context.Context, atlasMapping *v1alpha1.AtlasMapping, atlasMap *v1alpha1.AtlasMap, ready []corev1.Pod) (ctrl.Result, error) {
+	job := &batchv1.Job{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: mapping.JobName(atlasMapping), Namespace: atlasMapping.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+
+	if err == nil {
+		if job.DeletionTimestamp != nil {
+			setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonLoading, fmt.Sprintf("Waiting for Job %s to be deleted", job.Name))
+			return reconcile.Result{RequeueAfter: jobDeletionRetryInterval}, nil
+		}
+
+		// Jobs loading a previous content are discarded
+		if job.Annotations[mapping.JobContentHashAnnotation] != atlasMapping.Status.ContentHash {
+			return reconcile.Result{Requeue: true}, r.deleteJob(ctx, job)
+		}
+
+		finished, failed := mapping.JobFinished(job)
+		if !finished {
+			setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonLoading, fmt.Sprintf("Job %s is loading the mapping", job.Name))
+			return reconcile.Result{}, nil
+		}
+
+		if failed {
+			message := fmt.Sprintf("Job %s failed to load the mapping from PersistentVolumeClaim %s", job.Name, atlasMapping.Spec.Source.PersistentVolumeClaim.ClaimName)
+			r.Recorder.Event(atlasMapping, corev1.EventTypeWarning, reasonSyncFailed, message)
+			setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonSyncFailed, message)
+			if err := r.deleteJob(ctx, job); err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{}, goerrors.New(message)
+		}
+
+		loaded, err := mapping.JobPods(job)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		for _, status := range mapping.CurrentPodStatuses(ready, loaded) {
+			r.podLoaded(atlasMapping, status)
+		}
+		if err := r.deleteJob(ctx, job); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: jobDeletionRetryInterval}, nil
+	}
+
+	pending := mapping.PendingPods(ready, atlasMapping.Status.Pods)
+	if len(pending) == 0 {
+		setMappingSynced(atlasMapping)
+		return reconcile.Result{}, nil
+	}
+
+	job, err = mapping.LoaderJob(atlasMapping, atlasMap, pending, atlasMapping.Status.ContentHash)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := controllerutil.SetControllerReference(atlasMapping, job, r.Scheme); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.Client.Create(ctx, job); err != nil {
+		if errors.IsAlreadyExists(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	setSyncedCondition(atlasMapping, metav1.ConditionFalse, reasonLoading, fmt.Sprintf("Job %s is loading the mapping", job.Name))
+	return reconcile.Result{}, nil
+}
+
+func (r *AtlasMappingReconciler) deleteJob(ctx context.Context, job *batchv1.Job) error {
+	if err := r.Client.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// atlasMapPods lists the pods of the AtlasMap, selected as for its scale subresource
+func (r *AtlasMappingReconciler) atlasMapPods(ctx context.Context, atlasMap *v1alpha1.AtlasMap) ([]corev1.Pod, error) {
+	if len(atlasMap.Status.LabelSelector) == 0 {
+		return nil, nil
+	}
+	selector, err := labels.Parse(atlasMap.Status.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{}
+	if err := r.Client.List(ctx, pods, client.InNamespace(atlasMap.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+func (r *AtlasMappingReconciler) podLoaded(atlasMapping *v1alpha1.AtlasMapping, status v1alpha1.AtlasMappingPodStatus) {
+	atlasMapping.Status.Pods = append(atlasMapping.Status.Pods, status)
+	now := metav1.Now()
+	atlasMapping.Status.LastSyncTime = &now
+	r.Recorder.Eventf(atlasMapping, corev1.EventTypeNormal, reasonMappingLoaded, "Loaded the mapping into pod %s", status.Name)
+}
+
+func setMappingSynced(atlasMapping *v1alpha1.AtlasMapping) {
+	atlasMapping.Status.ObservedGeneration = atlasMapping.Generation
+	setSyncedCondition(atlasMapping, metav1.ConditionTrue, reasonMappingSynced,
+		fmt.Sprintf("Mapping loaded into %d AtlasMap pods", len(atlasMapping.Status.Pods)))
+}
+
+func setSyncedCondition(atlasMapping *v1alpha1.AtlasMapping, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&atlasMapping.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.AtlasMappingConditionSynced,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: atlasMapping.Generation,
+	})
+}
+
+// atlasMappingsReferencing maps an object to the AtlasMappings of its namespace that reference it through the given index
+func (r *AtlasMappingReconciler) atlasMappingsReferencing(index string) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		return r.atlasMappingRequests(object.GetNamespace(), index, object.GetName())
+	}
+}
+
+// atlasMappingsOfPod maps an AtlasMap pod to the AtlasMappings of its AtlasMap, so that mappings are loaded
+// into pods becoming ready and loaded again after restarts
+func (r *AtlasMappingReconciler) atlasMappingsOfPod(object client.Object) []reconcile.Request {
+	podLabels := object.GetLabels()
+	if podLabels["app.kubernetes.io/name"] != "atlasmap" || len(podLabels["app.kubernetes.io/instance"]) == 0 {
+		return nil
+	}
+	return r.atlasMappingRequests(object.GetNamespace(), atlasMapNameIndex, podLabels["app.kubernetes.io/instance"])
+}
+
+func (r *AtlasMappingReconciler) atlasMappingRequests(namespace string, index string, value string) []reconcile.Request {
+	atlasMappings := &v1alpha1.AtlasMappingList{}
+	if err := r.Client.List(context.Background(), atlasMappings, client.InNamespace(namespace), client.MatchingFields{index: value}); err != nil {
+		log.Error(err, "Error listing AtlasMappings referencing "+value, "index", index)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(atlasMappings.Items))
+	for _, atlasMapping := range atlasMappings.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: atlasMapping.Name, Namespace: atlasMapping.Namespace}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AtlasMappingReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("atlasmap-operator")
+	}
+
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &v1alpha1.AtlasMapping{}, atlasMapNameIndex, func(object client.Object) []string {
+		return []string{object.(*v1alpha1.AtlasMapping).Spec.AtlasMapName}
+	}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &v1alpha1.AtlasMapping{}, configMapIndex, func(object client.Object) []string {
+		if configMap := object.(*v1alpha1.AtlasMapping).Spec.Source.ConfigMap; configMap != nil {
+			return []string{configMap.Name}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &v1alpha1.AtlasMapping{}, secretIndex, func(object client.Object) []string {
+		if secret := object.(*v1alpha1.AtlasMapping).Spec.Source.Secret; secret != nil {
+			return []string{secret.Name}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.AtlasMapping{}, ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &v1alpha1.AtlasMap{}}, handler.EnqueueRequestsFromMapFunc(r.atlasMappingsReferencing(atlasMapNameIndex))).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.atlasMappingsOfPod)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.atlasMappingsReferencing(configMapIndex)), ctrlbuilder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.atlasMappingsReferencing(secretIndex)), ctrlbuilder.OnlyMetadata).
+		Complete(r)
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/atlasmap/atlasmap-operator/controllers/action"
+	"github.com/atlasmap/atlasmap-operator/controllers/mapping"
+)
+
+// NewCache creates the manager cache, which only holds the pods of AtlasMaps and the loader Jobs of AtlasMappings
+// rather than every pod and Job of the cluster
+var NewCache = cache.BuilderWithOptions(cache.Options{
+	SelectorsByObject: cache.SelectorsByObject{
+		&corev1.Pod{}:  {Label: action.PodSelector},
+		&batchv1.Job{}: {Label: mapping.JobSelector},
+	},
+})
+
+// UncachedObjects are read from the API server by the manager client. Their metadata is watched by the controllers,
+// and only the few referenced by AtlasMaps and AtlasMappings are read, so that the cache does not hold their content.
+var UncachedObjects = []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}}
@@ -0,0 +1,273 @@
+package capabilities
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	configv1client "github.com/openshift/client-go/config/clientset/versioned"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RefreshInterval is how often the cluster capabilities are detected again
+const RefreshInterval = 5 * time.Minute
+
+// RetryInterval is how often the detection is retried until it first succeeds
+const RetryInterval = 10 * time.Second
+
+const (
+	consoleGroupVersion    = "console.openshift.io/v1"
+	monitoringGroupVersion = "monitoring.coreos.com/v1"
+)
+
+var log = logf.Log.WithName("capabilities")
+
+// Capabilities describes the optional APIs and platform features served by the cluster
+type Capabilities struct {
+	// Routes is true on OpenShift, which serves route.openshift.io/v1
+	Routes bool `json:"routes"`
+	// ConsoleLinks is true when the OpenShift console ConsoleLink resource is served
+	ConsoleLinks bool `json:"consoleLinks"`
+	// OpenShiftVersion is the version of the OpenShift cluster, 3 for OpenShift releases without a ClusterVersion
+	OpenShiftVersion string `json:"openShiftVersion,omitempty"`
+	// IngressAPIVersion is the most recent Ingress API version served, AtlasMap is only exposed through networking.k8s.io/v1 Ingresses
+	IngressAPIVersion string `json:"ingressAPIVersion,omitempty"`
+	// AutoscalerAPIVersion is the HorizontalPodAutoscaler API version used for AtlasMap
+	AutoscalerAPIVersion string `json:"autoscalerAPIVersion"`
+	// Monitoring is true when the Prometheus Operator ServiceMonitor and PrometheusRule resources are served
+	Monitoring bool `json:"monitoring"`
+	// GatewayAPIVersion is the most recent Gateway API version served, if any
+	GatewayAPIVersion string `json:"gatewayAPIVersion,omitempty"`
+}
+
+// Detector detects the cluster capabilities and caches them between periodic refreshes,
+// so that the controller and its actions do not query the discovery API on each reconcile
+type Detector struct {
+	discovery      discovery.DiscoveryInterface
+	clusterVersion func(ctx context.Context) (string, error)
+	interval       time.Duration
+
+	lock         sync.RWMutex
+	capabilities Capabilities
+	detected     bool
+	listeners    []func(Capabilities)
+}
+
+// defaultCapabilities are assumed until the capabilities are first detected, without any optional API
+var defaultCapabilities = Capabilities{AutoscalerAPIVersion: "autoscaling/v2beta2"}
+
+// NewDetector returns a Detector for the cluster of the given configuration. The capabilities are detected right away,
+// a failure is logged and the conservative defaults are reported until a later refresh succeeds.
+func NewDetector(config *rest.Config) (*Detector, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	configClient, err := configv1client.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	detector := &Detector{
+		discovery: discoveryClient,
+		clusterVersion: func(ctx context.Context) (string, error) {
+			clusterVersion, err := configClient.ConfigV1().ClusterVersions().Get(ctx, "version", metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				// The ClusterVersion API was introduced in OpenShift 4
+				return "3", nil
+			} else if err != nil {
+				return "", err
+			}
+			if len(clusterVersion.Status.History) == 0 {
+				return clusterVersion.Status.Desired.Version, nil
+			}
+			// The latest version from the history
+			return clusterVersion.Status.History[0].Version, nil
+		},
+		interval:     RefreshInterval,
+		capabilities: defaultCapabilities,
+	}
+
+	if err := detector.Refresh(context.TODO()); err != nil {
+		log.Error(err, "Failed to detect cluster capabilities, retrying", "interval", RetryInterval)
+	}
+	return detector, nil
+}
+
+// Get returns the capabilities detected by the last successful refresh
+func (d *Detector) Get() Capabilities {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.capabilities
+}
+
+// Detected tells whether the capabilities have been detected, rather than being the defaults
+func (d *Detector) Detected() bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.detected
+}
+
+// OnChange registers a function called with the capabilities whenever a refresh detects different ones
+func (d *Detector) OnChange(listener func(Capabilities)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.listeners = append(d.listeners, listener)
+}
+
+// Refresh detects the cluster capabilities again
+func (d *Detector) Refresh(ctx context.Context) error {
+	if d.discovery == nil {
+		return nil
+	}
+	capabilities, err := detect(ctx, d.discovery, d.clusterVersion)
+	if err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	changed := !d.detected || !equality.Semantic.DeepEqual(d.capabilities, capabilities)
+	if changed {
+		log.Info("Detected cluster capabilities",
+			"routes", capabilities.Routes,
+			"consoleLinks", capabilities.ConsoleLinks,
+			"openShiftVersion", capabilities.OpenShiftVersion,
+			"ingressAPIVersion", capabilities.IngressAPIVersion,
+			"autoscalerAPIVersion", capabilities.AutoscalerAPIVersion,
+			"monitoring", capabilities.Monitoring,
+			"gatewayAPIVersion", capabilities.GatewayAPIVersion)
+		d.capabilities = capabilities
+		d.detected = true
+	}
+	listeners := d.listeners
+	d.lock.Unlock()
+
+	if changed {
+		for _, listener := range listeners {
+			listener(capabilities)
+		}
+	}
+	return nil
+}
+
+// Start implements manager.Runnable and refreshes the capabilities until the context is done,
+// retrying more often while they have never been detected
+func (d *Detector) Start(ctx context.Context) error {
+	for {
+		interval := d.interval
+		if err := d.Refresh(ctx); err != nil {
+			log.Error(err, "Failed to detect cluster capabilities")
+			if !d.Detected() {
+				interval = RetryInterval
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, as every replica needs the capabilities
+func (d *Detector) NeedLeaderElection() bool {
+	return false
+}
+
+// ServeHTTP reports the capabilities as JSON
+func (d *Detector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d.Get()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func detect(ctx context.Context, client discovery.DiscoveryInterface, clusterVersion func(context.Context) (string, error)) (Capabilities, error) {
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	served := make(map[string]bool)
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			served[version.GroupVersion] = true
+		}
+	}
+
+	capabilities := Capabilities{
+		Routes:               served["route.openshift.io/v1"],
+		IngressAPIVersion:    firstServed(served, "networking.k8s.io/v1", "networking.k8s.io/v1beta1", "extensions/v1beta1"),
+		AutoscalerAPIVersion: firstServed(served, "autoscaling/v2", "autoscaling/v2beta2"),
+		GatewayAPIVersion:    firstServed(served, "gateway.networking.k8s.io/v1", "gateway.networking.k8s.io/v1beta1"),
+	}
+
+	if len(capabilities.AutoscalerAPIVersion) == 0 {
+		capabilities.AutoscalerAPIVersion = "autoscaling/v2beta2"
+	}
+
+	if served[consoleGroupVersion] {
+		if capabilities.ConsoleLinks, err = servesResources(client, consoleGroupVersion, "consolelinks"); err != nil {
+			return Capabilities{}, err
+		}
+	}
+
+	if served[monitoringGroupVersion] {
+		if capabilities.Monitoring, err = servesResources(client, monitoringGroupVersion, "servicemonitors", "prometheusrules"); err != nil {
+			return Capabilities{}, err
+		}
+	}
+
+	if capabilities.Routes && clusterVersion != nil {
+		if capabilities.OpenShiftVersion, err = clusterVersion(ctx); err != nil {
+			return Capabilities{}, err
+		}
+	}
+
+	return capabilities, nil
+}
+
+func firstServed(served map[string]bool, groupVersions ...string) string {
+	for _, groupVersion := range groupVersions {
+		if served[groupVersion] {
+			return groupVersion
+		}
+	}
+	return ""
+}
+
+func servesResources(client discovery.DiscoveryInterface, groupVersion string, names ...string) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(groupVersion)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		found := false
+		for _, resource := range resources.APIResources {
+			if resource.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// NewStaticDetector returns a Detector that always reports the given capabilities, e.g. for tests
+func NewStaticDetector(capabilities Capabilities) *Detector {
+	return &Detector{capabilities: capabilities, detected: true}
+}
@@ -0,0 +1,111 @@
+package capabilities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newDetector(resources ...*metav1.APIResourceList) *Detector {
+	return &Detector{
+		discovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: resources}},
+		clusterVersion: func(context.Context) (string, error) {
+			return "4.8.2", nil
+		},
+	}
+}
+
+func resourceList(groupVersion string, names ...string) *metav1.APIResourceList {
+	list := &metav1.APIResourceList{GroupVersion: groupVersion}
+	for _, name := range names {
+		list.APIResources = append(list.APIResources, metav1.APIResource{Name: name})
+	}
+	return list
+}
+
+func TestKubernetes(t *testing.T) {
+	detector := newDetector(
+		resourceList("networking.k8s.io/v1", "ingresses"),
+		resourceList("autoscaling/v2beta2", "horizontalpodautoscalers"),
+		resourceList("monitoring.coreos.com/v1", "servicemonitors"),
+	)
+	assert.NoError(t, detector.Refresh(context.TODO()))
+
+	capabilities := detector.Get()
+	assert.False(t, capabilities.Routes)
+	assert.False(t, capabilities.ConsoleLinks)
+	assert.Empty(t, capabilities.OpenShiftVersion)
+	assert.Equal(t, "networking.k8s.io/v1", capabilities.IngressAPIVersion)
+	assert.Equal(t, "autoscaling/v2beta2", capabilities.AutoscalerAPIVersion)
+	// PrometheusRules are not served
+	assert.False(t, capabilities.Monitoring)
+	assert.Empty(t, capabilities.GatewayAPIVersion)
+}
+
+func TestOpenShift(t *testing.T) {
+	detector := newDetector(
+		resourceList("route.openshift.io/v1", "routes"),
+		resourceList("console.openshift.io/v1", "consolelinks"),
+		resourceList("autoscaling/v2", "horizontalpodautoscalers"),
+		resourceList("autoscaling/v2beta2", "horizontalpodautoscalers"),
+		resourceList("monitoring.coreos.com/v1", "servicemonitors", "prometheusrules"),
+		resourceList("gateway.networking.k8s.io/v1beta1", "gateways", "httproutes"),
+	)
+	assert.NoError(t, detector.Refresh(context.TODO()))
+
+	capabilities := detector.Get()
+	assert.True(t, capabilities.Routes)
+	assert.True(t, capabilities.ConsoleLinks)
+	assert.Equal(t, "4.8.2", capabilities.OpenShiftVersion)
+	assert.Equal(t, "autoscaling/v2", capabilities.AutoscalerAPIVersion)
+	assert.True(t, capabilities.Monitoring)
+	assert.Equal(t, "gateway.networking.k8s.io/v1beta1", capabilities.GatewayAPIVersion)
+}
+
+func TestRecoversFromFailedDetection(t *testing.T) {
+	detector := newDetector(resourceList("route.openshift.io/v1", "routes"))
+	detector.capabilities = defaultCapabilities
+	clusterVersionErr := errors.New("unavailable")
+	detector.clusterVersion = func(context.Context) (string, error) {
+		return "4.8.2", clusterVersionErr
+	}
+	var notified []Capabilities
+	detector.OnChange(func(capabilities Capabilities) {
+		notified = append(notified, capabilities)
+	})
+
+	// The conservative defaults are kept until the detection succeeds
+	assert.Error(t, detector.Refresh(context.TODO()))
+	assert.False(t, detector.Detected())
+	assert.Equal(t, defaultCapabilities, detector.Get())
+	assert.Empty(t, notified)
+
+	clusterVersionErr = nil
+	assert.NoError(t, detector.Refresh(context.TODO()))
+	assert.True(t, detector.Detected())
+	assert.True(t, detector.Get().Routes)
+	assert.Equal(t, []Capabilities{detector.Get()}, notified)
+
+	// Listeners are only notified of changes
+	assert.NoError(t, detector.Refresh(context.TODO()))
+	assert.Len(t, notified, 1)
+}
+
+func TestServeHTTP(t *testing.T) {
+	detector := newDetector(resourceList("route.openshift.io/v1", "routes"))
+	assert.NoError(t, detector.Refresh(context.TODO()))
+
+	recorder := httptest.NewRecorder()
+	detector.ServeHTTP(recorder, httptest.NewRequest("GET", "/capabilities", nil))
+
+	capabilities := Capabilities{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &capabilities))
+	assert.Equal(t, detector.Get(), capabilities)
+}
@@ -0,0 +1,138 @@
+package catalog
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+)
+
+// LatestVersion is the version that resolves to the most recent catalog entry
+const LatestVersion = "latest"
+
+// SupportStatus describes whether the operator supports an AtlasMap version
+type SupportStatus string
+
+const (
+	// Supported --
+	Supported SupportStatus = "Supported"
+	// Deprecated --
+	Deprecated SupportStatus = "Deprecated"
+	// Unsupported --
+	Unsupported SupportStatus = "Unsupported"
+)
+
+const (
+	springBoot1ProbePath = "/management/health"
+	springBoot2ProbePath = "/actuator/health"
+)
+
+// Ports are the container ports exposed by an AtlasMap image
+type Ports struct {
+	HTTP       int32
+	Jolokia    int32
+	Prometheus int32
+}
+
+// Entry describes how to run the AtlasMap versions matching a semantic version range
+type Entry struct {
+	// Range is the semantic version constraint matched by the entry
+	Range string
+	// ProbePath is the HTTP path of the liveness and readiness probes
+	ProbePath string
+	// Ports are the container ports exposed by the image
+	Ports Ports
+	// JavaOptions are the default JVM options passed to the image
+	JavaOptions string
+	// Status tells whether the versions are supported or deprecated
+	Status SupportStatus
+
+	constraint *semver.Constraints
+}
+
+// UnsupportedVersionError is returned for versions that are not valid or not in the catalog
+type UnsupportedVersionError struct {
+	Version string
+	Reason  string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("AtlasMap version %q is not supported: %s", e.Version, e.Reason)
+}
+
+var defaultPorts = Ports{
+	HTTP:       8585,
+	Jolokia:    8778,
+	Prometheus: 9779,
+}
+
+// entries are ordered from the most recent to the oldest version range
+var entries = []*Entry{
+	{
+		Range:       ">= 2.0.0",
+		ProbePath:   springBoot2ProbePath,
+		Ports:       defaultPorts,
+		JavaOptions: "-XX:MaxRAMPercentage=75.0",
+		Status:      Supported,
+	},
+	{
+		Range:     ">= 1.43.0, < 2.0.0",
+		ProbePath: springBoot2ProbePath,
+		Ports:     defaultPorts,
+		Status:    Deprecated,
+	},
+	{
+		Range:     ">= 1.0.0, < 1.43.0",
+		ProbePath: springBoot1ProbePath,
+		Ports:     defaultPorts,
+		Status:    Deprecated,
+	},
+}
+
+func init() {
+	for _, entry := range entries {
+		entry.constraint = mustConstraint(entry.Range)
+	}
+}
+
+func mustConstraint(c string) *semver.Constraints {
+	constraint, err := semver.NewConstraint(c)
+	if err != nil {
+		panic(err)
+	}
+	return constraint
+}
+
+// Latest returns the catalog entry of the most recent AtlasMap versions
+func Latest() *Entry {
+	return entries[0]
+}
+
+// Lookup returns the catalog entry matching the given AtlasMap version. An empty version or 'latest'
+// match the most recent entry. Pre-release and build metadata are ignored, so that snapshots of a
+// release match the same entry as the release itself.
+func Lookup(version string) (*Entry, error) {
+	if len(version) == 0 || version == LatestVersion {
+		return Latest(), nil
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, &UnsupportedVersionError{Version: version, Reason: "not a semantic version"}
+	}
+
+	release, err := v.SetPrerelease("")
+	if err != nil {
+		return nil, err
+	}
+	release, err = release.SetMetadata("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.constraint.Check(&release) {
+			return entry, nil
+		}
+	}
+	return nil, &UnsupportedVersionError{Version: version, Reason: "no matching version range"}
+}
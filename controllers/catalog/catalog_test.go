@@ -0,0 +1,41 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		version   string
+		probePath string
+		status    SupportStatus
+	}{
+		{"", springBoot2ProbePath, Supported},
+		{"latest", springBoot2ProbePath, Supported},
+		{"2.3.0", springBoot2ProbePath, Supported},
+		{"2.3.0-SNAPSHOT", springBoot2ProbePath, Supported},
+		{"2.0.0-M1", springBoot2ProbePath, Supported},
+		{"1.43.0", springBoot2ProbePath, Deprecated},
+		{"1.43.0-SNAPSHOT", springBoot2ProbePath, Deprecated},
+		{"1.42.9", springBoot1ProbePath, Deprecated},
+		{"1.38", springBoot1ProbePath, Deprecated},
+	}
+
+	for _, test := range tests {
+		entry, err := Lookup(test.version)
+		if assert.NoError(t, err, test.version) {
+			assert.Equal(t, test.probePath, entry.ProbePath, test.version)
+			assert.Equal(t, test.status, entry.Status, test.version)
+			assert.Equal(t, int32(8585), entry.Ports.HTTP, test.version)
+		}
+	}
+}
+
+func TestLookupUnsupported(t *testing.T) {
+	for _, version := range []string{"0.9.0", "nightly", "1.x.y"} {
+		_, err := Lookup(version)
+		assert.IsType(t, &UnsupportedVersionError{}, err, version)
+	}
+}
@@ -1,26 +1,66 @@
 package config
 
-// *************************************
-// THIS FILE IS GENERATED - DO NOT EDIT
-// *************************************
-
 import "github.com/atlasmap/atlasmap-operator/controllers/util"
 
 var DefaultOperatorImage = "quay.io/atlasmap/atlasmap-operator"
 var DefaultOperatorVersion = "latest"
 
+const (
+	// AtlasMapImageNameEnv is the environment variable that overrides the default AtlasMap image name
+	AtlasMapImageNameEnv = "ATLASMAP_IMAGE_NAME"
+	// AtlasMapImageVersionEnv is the environment variable that overrides the default AtlasMap image version
+	AtlasMapImageVersionEnv = "ATLASMAP_IMAGE_VERSION"
+	// RelatedImageEnv is the environment variable set by OLM with the AtlasMap image reference
+	RelatedImageEnv = "RELATED_IMAGE_ATLASMAP"
+	// MappingLoaderImageEnv is the environment variable that overrides the image of the Jobs loading mappings from volumes
+	MappingLoaderImageEnv = "MAPPING_LOADER_IMAGE"
+)
+
 // AtlasMapConfig --
 type AtlasMapConfig struct {
 	AtlasMapImage string
 	Version       string
+	// Image is a complete image reference, such as a digest, used in place of AtlasMapImage and Version
+	Image string
+	// MappingLoaderImage is the image of the Jobs loading mappings from volumes, which must provide a shell and curl
+	MappingLoaderImage string
 }
 
 // DefaultConfiguration --
 var DefaultConfiguration = AtlasMapConfig{
-	AtlasMapImage: "docker.io/atlasmap/atlasmap",
-	Version:       "latest",
+	AtlasMapImage:      "docker.io/atlasmap/atlasmap",
+	Version:            "latest",
+	MappingLoaderImage: "docker.io/curlimages/curl:7.79.1",
 }
 
 func (c *AtlasMapConfig) GetAtlasMapImage() string {
+	if len(c.Image) > 0 {
+		return c.Image
+	}
 	return util.ImageName(c.AtlasMapImage, c.Version)
 }
+
+// ApplyOverrides configures the default AtlasMap image. The related image reference provided by OLM is applied
+// first, so that AtlasMap versions are pulled from the same repository, and the image name and version then take
+// precedence over it
+func (c *AtlasMapConfig) ApplyOverrides(imageName string, version string, relatedImage string) {
+	if len(relatedImage) > 0 {
+		repository, tag, digest := util.ParseImage(relatedImage)
+		c.AtlasMapImage = repository
+		if len(digest) > 0 {
+			c.Image = relatedImage
+		} else if len(tag) > 0 {
+			c.Version = tag
+		}
+	}
+
+	if len(imageName) > 0 {
+		c.AtlasMapImage = imageName
+		c.Image = ""
+	}
+
+	if len(version) > 0 {
+		c.Version = version
+		c.Image = ""
+	}
+}
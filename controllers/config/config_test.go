@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	c := AtlasMapConfig{AtlasMapImage: "docker.io/atlasmap/atlasmap", Version: "latest"}
+	c.ApplyOverrides("", "", "")
+	assert.Equal(t, "docker.io/atlasmap/atlasmap:latest", c.GetAtlasMapImage())
+
+	c = AtlasMapConfig{AtlasMapImage: "docker.io/atlasmap/atlasmap", Version: "latest"}
+	c.ApplyOverrides("mirror.local/atlasmap/atlasmap", "2.3.0", "")
+	assert.Equal(t, "mirror.local/atlasmap/atlasmap:2.3.0", c.GetAtlasMapImage())
+
+	c = AtlasMapConfig{AtlasMapImage: "docker.io/atlasmap/atlasmap", Version: "latest"}
+	c.ApplyOverrides("", "", "mirror.local/atlasmap/atlasmap:2.3.0")
+	assert.Equal(t, "mirror.local/atlasmap/atlasmap", c.AtlasMapImage)
+	assert.Equal(t, "2.3.0", c.Version)
+	assert.Equal(t, "mirror.local/atlasmap/atlasmap:2.3.0", c.GetAtlasMapImage())
+
+	c = AtlasMapConfig{AtlasMapImage: "docker.io/atlasmap/atlasmap", Version: "latest"}
+	c.ApplyOverrides("", "", "mirror.local/atlasmap/atlasmap@sha256:abc123")
+	assert.Equal(t, "mirror.local/atlasmap/atlasmap", c.AtlasMapImage)
+	assert.Equal(t, "mirror.local/atlasmap/atlasmap@sha256:abc123", c.GetAtlasMapImage())
+
+	c = AtlasMapConfig{AtlasMapImage: "docker.io/atlasmap/atlasmap", Version: "latest"}
+	c.ApplyOverrides("", "2.3.0", "mirror.local/atlasmap/atlasmap@sha256:abc123")
+	assert.Equal(t, "mirror.local/atlasmap/atlasmap:2.3.0", c.GetAtlasMapImage())
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	consolev1 "github.com/openshift/api/console/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const consoleLinkSweepInterval = 10 * time.Minute
+
+// consoleLinkSweeper periodically deletes the ConsoleLinks whose AtlasMap no longer exists, e.g. because
+// the AtlasMap or its namespace was deleted while the operator was not running, or its finalizer was removed
+type consoleLinkSweeper struct {
+	client       client.Client
+	reader       client.Reader
+	capabilities *capabilities.Detector
+	interval     time.Duration
+}
+
+func newConsoleLinkSweeper(client client.Client, reader client.Reader, capabilities *capabilities.Detector, interval time.Duration) *consoleLinkSweeper {
+	return &consoleLinkSweeper{
+		client:       client,
+		reader:       reader,
+		capabilities: capabilities,
+		interval:     interval,
+	}
+}
+
+// Start implements manager.Runnable
+func (s *consoleLinkSweeper) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, s.sweep, s.interval)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *consoleLinkSweeper) NeedLeaderElection() bool {
+	return true
+}
+
+func (s *consoleLinkSweeper) sweep(ctx context.Context) {
+	if !s.capabilities.Get().ConsoleLinks {
+		return
+	}
+
+	links := &consolev1.ConsoleLinkList{}
+	if err := s.reader.List(ctx, links, client.HasLabels{util.ConsoleLinkNamespaceLabel, util.ConsoleLinkNameLabel}); err != nil {
+		log.Error(err, "Error listing ConsoleLinks")
+		return
+	}
+
+	for i := range links.Items {
+		link := &links.Items[i]
+		key := types.NamespacedName{
+			Namespace: link.Labels[util.ConsoleLinkNamespaceLabel],
+			Name:      link.Labels[util.ConsoleLinkNameLabel],
+		}
+
+		// The AtlasMap is read from the API server, as the cache may not have seen it yet
+		err := s.reader.Get(ctx, key, &v1alpha1.AtlasMap{})
+		if err == nil {
+			continue
+		} else if !errors.IsNotFound(err) {
+			log.Error(err, "Error retrieving AtlasMap", "AtlasMap.Namespace", key.Namespace, "AtlasMap.Name", key.Name)
+			continue
+		}
+
+		log.Info("Deleting orphaned ConsoleLink", "ConsoleLink.Name", link.Name, "AtlasMap.Namespace", key.Namespace, "AtlasMap.Name", key.Name)
+		if err := s.client.Delete(ctx, link); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Error deleting ConsoleLink", "ConsoleLink.Name", link.Name)
+		}
+	}
+}
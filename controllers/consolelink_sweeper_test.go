@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	consolev1 "github.com/openshift/api/console/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestConsoleLink(name string, labels map[string]string) *consolev1.ConsoleLink {
+	return &consolev1.ConsoleLink{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestConsoleLinkSweeper(t *testing.T) {
+	atlasMap := &v1alpha1.AtlasMap{ObjectMeta: metav1.ObjectMeta{Name: "atlasmap", Namespace: "test"}}
+	labels := func(namespace string, name string) map[string]string {
+		return map[string]string{util.ConsoleLinkNamespaceLabel: namespace, util.ConsoleLinkNameLabel: name}
+	}
+	c := newTestClient(t, atlasMap,
+		newTestConsoleLink("live", labels("test", "atlasmap")),
+		newTestConsoleLink("orphaned", labels("test", "deleted")),
+		newTestConsoleLink("unlabelled", nil),
+	)
+
+	newConsoleLinkSweeper(c, c, capabilities.NewStaticDetector(capabilities.Capabilities{ConsoleLinks: true}), consoleLinkSweepInterval).sweep(context.TODO())
+
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "live"}, &consolev1.ConsoleLink{}))
+	assert.True(t, errors.IsNotFound(c.Get(context.TODO(), client.ObjectKey{Name: "orphaned"}, &consolev1.ConsoleLink{})))
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "unlabelled"}, &consolev1.ConsoleLink{}))
+}
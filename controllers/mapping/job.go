@@ -0,0 +1,183 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/config"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Annotations of the loader Job recording what it loads, so that its outcome can be applied to the AtlasMapping
+const (
+	JobContentHashAnnotation = "atlasmap.io/content-hash"
+	JobPodsAnnotation        = "atlasmap.io/pods"
+)
+
+const (
+	mappingVolume    = "mapping"
+	mappingMountPath = "/mapping"
+	mappingFileEnv   = "MAPPING_FILE"
+	jobBackoffLimit  = 2
+	loaderAppName    = "atlasmap-mapping-loader"
+)
+
+// JobSelector selects the loader Jobs of every AtlasMapping
+var JobSelector = labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": loaderAppName})
+
+// JobName is the name of the Job loading the mapping stored on a PersistentVolumeClaim
+func JobName(atlasMapping *v1alpha1.AtlasMapping) string {
+	return atlasMapping.Name + "-loader"
+}
+
+// LoaderJob creates a Job that mounts the PersistentVolumeClaim of the AtlasMapping and loads the mapping file into
+// the given pods with curl. It runs the mapping loader image configured on the operator, pulled with the image pull
+// secrets of the AtlasMap, so that a mirror of the loader image can live in the same registry as the AtlasMap image
+func LoaderJob(atlasMapping *v1alpha1.AtlasMapping, atlasMap *v1alpha1.AtlasMap, pods []corev1.Pod, contentHash string) (*batchv1.Job, error) {
+	claim := atlasMapping.Spec.Source.PersistentVolumeClaim
+
+	statuses := make([]v1alpha1.AtlasMappingPodStatus, 0, len(pods))
+	commands := make([]string, 0, len(pods)+1)
+	commands = append(commands, "set -e")
+	for i := range pods {
+		statuses = append(statuses, PodStatus(&pods[i]))
+		command, err := curlCommand(PodTarget(&pods[i], atlasMap), atlasMapping)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+	loadedPods, err := json.Marshal(statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	backoffLimit := int32(jobBackoffLimit)
+	return &batchv1.Job{
+		TypeMeta: v1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      JobName(atlasMapping),
+			Namespace: atlasMapping.Namespace,
+			// The AtlasMap selector labels are left out, so that the Service does not route to the loader
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       loaderAppName,
+				"app.kubernetes.io/part-of":    "atlasmap",
+				"app.kubernetes.io/managed-by": "atlasmap-operator",
+				"atlasmap.io/name":             atlasMap.Name,
+				"atlasmap.io/mapping":          atlasMapping.Name,
+			},
+			Annotations: map[string]string{
+				JobContentHashAnnotation: contentHash,
+				JobPodsAnnotation:        string(loadedPods),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: atlasMap.Spec.ImagePullSecrets,
+					Containers: []corev1.Container{
+						{
+							Name:    "loader",
+							Image:   config.DefaultConfiguration.MappingLoaderImage,
+							Command: []string{"/bin/sh", "-c", strings.Join(commands, "\n")},
+							Env: []corev1.EnvVar{
+								{
+									Name:  mappingFileEnv,
+									Value: path.Join(mappingMountPath, claim.Path),
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      mappingVolume,
+									MountPath: mappingMountPath,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: mappingVolume,
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: claim.ClaimName,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// JobPods returns the pods that the Job loads the mapping into
+func JobPods(job *batchv1.Job) ([]v1alpha1.AtlasMappingPodStatus, error) {
+	var statuses []v1alpha1.AtlasMappingPodStatus
+	if err := json.Unmarshal([]byte(job.Annotations[JobPodsAnnotation]), &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// JobFinished reports whether the Job completed or failed
+func JobFinished(job *batchv1.Job) (finished bool, failed bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, false
+		case batchv1.JobFailed:
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// curlCommand stores the mapping file of the Job in the target. HTTPS targets are reached through the host name of
+// their serving certificate, resolved to the pod address, and verified with the OpenShift service CA
+func curlCommand(target Target, atlasMapping *v1alpha1.AtlasMapping) (string, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return "", err
+	}
+	args := []string{
+		"curl", "--fail", "--silent", "--show-error", "-X", "PUT",
+		"-H", shellQuote("Content-Type: " + ContentType(atlasMapping.MappingFormat())),
+		"--data-binary", fmt.Sprintf(`"@$%s"`, mappingFileEnv),
+	}
+	if len(target.ServerName) > 0 {
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		args = append(args, "--cacert", shellQuote(ServiceCAFile), "--resolve", shellQuote(fmt.Sprintf("%s:%s:%s", target.ServerName, port, host)))
+		u.Host = net.JoinHostPort(target.ServerName, port)
+	}
+	u.Path = Path(atlasMapping.MappingFormat(), atlasMapping.Spec.DefinitionID)
+	args = append(args, shellQuote(u.String()))
+	return strings.Join(args, " "), nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,128 @@
+package mapping
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+)
+
+// ServiceCAFile is the OpenShift service CA bundle mounted into every pod, which signs the serving certificates of AtlasMap pods
+const ServiceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+
+const defaultTimeout = 30 * time.Second
+
+// Target is an AtlasMap instance that a mapping is loaded into
+type Target struct {
+	// URL is the base URL of the instance, e.g. http://10.0.0.1:8585
+	URL string
+	// ServerName is the host name verified against the serving certificate of instances served over HTTPS
+	ServerName string
+}
+
+// Loader loads mapping definitions into AtlasMap instances through the AtlasMap REST API
+type Loader struct {
+	// RootCAs verifies the serving certificates of the instances. The system pool is used when nil
+	RootCAs *x509.CertPool
+	// Timeout bounds each request
+	Timeout time.Duration
+}
+
+// NewLoader creates a Loader trusting the system CAs and, when it is mounted, the OpenShift service CA
+func NewLoader() (*Loader, error) {
+	loader := &Loader{Timeout: defaultTimeout}
+	ca, err := ioutil.ReadFile(ServiceCAFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return loader, nil
+		}
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %s", ServiceCAFile)
+	}
+	loader.RootCAs = pool
+	return loader, nil
+}
+
+// Error is returned when AtlasMap rejects a mapping
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("AtlasMap returned %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("AtlasMap returned %d", e.StatusCode)
+}
+
+// Load stores the mapping content as the mapping definition with the given identifier of the target instance
+func (l *Loader) Load(ctx context.Context, target Target, format v1alpha1.AtlasMappingFormat, definitionID int32, content []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(target.URL, "/")+Path(format, definitionID), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", ContentType(format))
+
+	// The server name differs between instances, so that a transport is used for each request
+	transport := &http.Transport{
+		// Pods are reached directly, never through an HTTP proxy
+		Proxy: nil,
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    l.RootCAs,
+			ServerName: target.ServerName,
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	timeout := l.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	response, err := (&http.Client{Transport: transport, Timeout: timeout}).Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+		return &Error{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	return nil
+}
+
+// Path is the AtlasMap REST API path that mapping definitions of the given format are stored at.
+// ADM archives are uploaded as ZIP files
+func Path(format v1alpha1.AtlasMappingFormat, definitionID int32) string {
+	fileType := "ZIP"
+	if format == v1alpha1.AtlasMappingFormatJSON {
+		fileType = "JSON"
+	}
+	return fmt.Sprintf("/v2/atlas/mapping/%s/%d", fileType, definitionID)
+}
+
+// ContentType is the media type of mapping content of the given format
+func ContentType(format v1alpha1.AtlasMappingFormat) string {
+	if format == v1alpha1.AtlasMappingFormatJSON {
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
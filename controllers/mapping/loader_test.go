@@ -0,0 +1,94 @@
+package mapping
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+// atlasMapStandIn records the mappings stored through the AtlasMap REST API
+type atlasMapStandIn struct {
+	method      string
+	path        string
+	contentType string
+	body        []byte
+	status      int
+}
+
+func (s *atlasMapStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.method = r.Method
+	s.path = r.URL.Path
+	s.contentType = r.Header.Get("Content-Type")
+	s.body, _ = ioutil.ReadAll(r.Body)
+	if s.status != 0 {
+		http.Error(w, "invalid mapping", s.status)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestLoadJSON(t *testing.T) {
+	standIn := &atlasMapStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	loader := &Loader{}
+	content := []byte(`{"AtlasMapping":{}}`)
+	assert.NoError(t, loader.Load(context.TODO(), Target{URL: server.URL}, v1alpha1.AtlasMappingFormatJSON, 0, content))
+	assert.Equal(t, http.MethodPut, standIn.method)
+	assert.Equal(t, "/v2/atlas/mapping/JSON/0", standIn.path)
+	assert.Equal(t, "application/json", standIn.contentType)
+	assert.Equal(t, content, standIn.body)
+}
+
+func TestLoadADM(t *testing.T) {
+	standIn := &atlasMapStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	loader := &Loader{}
+	content := []byte{0x50, 0x4b, 0x03, 0x04}
+	assert.NoError(t, loader.Load(context.TODO(), Target{URL: server.URL + "/"}, v1alpha1.AtlasMappingFormatADM, 2, content))
+	assert.Equal(t, "/v2/atlas/mapping/ZIP/2", standIn.path)
+	assert.Equal(t, "application/octet-stream", standIn.contentType)
+	assert.Equal(t, content, standIn.body)
+}
+
+func TestLoadRejected(t *testing.T) {
+	standIn := &atlasMapStandIn{status: http.StatusBadRequest}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	loader := &Loader{}
+	err := loader.Load(context.TODO(), Target{URL: server.URL}, v1alpha1.AtlasMappingFormatJSON, 0, []byte("{}"))
+	if assert.Error(t, err) {
+		loadErr, ok := err.(*Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, loadErr.StatusCode)
+		assert.Equal(t, "invalid mapping", loadErr.Message)
+	}
+}
+
+func TestLoadHTTPS(t *testing.T) {
+	standIn := &atlasMapStandIn{}
+	server := httptest.NewTLSServer(standIn)
+	defer server.Close()
+
+	// The serving certificate is not trusted by default
+	loader := &Loader{}
+	assert.Error(t, loader.Load(context.TODO(), Target{URL: server.URL, ServerName: "example.com"}, v1alpha1.AtlasMappingFormatJSON, 0, []byte("{}")))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	loader.RootCAs = pool
+	assert.NoError(t, loader.Load(context.TODO(), Target{URL: server.URL, ServerName: "example.com"}, v1alpha1.AtlasMappingFormatJSON, 0, []byte("{}")))
+
+	// The certificate must match the server name
+	assert.Error(t, loader.Load(context.TODO(), Target{URL: server.URL, ServerName: "atlasmap.test.svc"}, v1alpha1.AtlasMappingFormatJSON, 0, []byte("{}")))
+}
@@ -0,0 +1,102 @@
+package mapping
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	atlasMapContainer = "atlasmap"
+	httpPortName      = "http"
+	defaultPort       = 8585
+)
+
+// ReadyPods returns the AtlasMap pods that serve requests. Mappings are loaded into each of them, since the
+// Service would only reach one of the pods, which do not share their workspace unless storage is shared
+func ReadyPods(pods []corev1.Pod) []corev1.Pod {
+	ready := make([]corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || len(pod.Status.PodIP) == 0 {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready = append(ready, pod)
+				break
+			}
+		}
+	}
+	return ready
+}
+
+// PodStatus identifies the pod and the restart of its AtlasMap container, which loses the loaded mappings
+func PodStatus(pod *corev1.Pod) v1alpha1.AtlasMappingPodStatus {
+	status := v1alpha1.AtlasMappingPodStatus{Name: pod.Name, UID: pod.UID}
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.Name == atlasMapContainer {
+			status.RestartCount = container.RestartCount
+		}
+	}
+	return status
+}
+
+// PendingPods returns the pods that the mapping has not been loaded into since they last (re)started
+func PendingPods(pods []corev1.Pod, loaded []v1alpha1.AtlasMappingPodStatus) []corev1.Pod {
+	pending := make([]corev1.Pod, 0, len(pods))
+	for i := range pods {
+		if !containsPodStatus(loaded, PodStatus(&pods[i])) {
+			pending = append(pending, pods[i])
+		}
+	}
+	return pending
+}
+
+// CurrentPodStatuses keeps the loaded pods that still run the container the mapping was loaded into
+func CurrentPodStatuses(pods []corev1.Pod, loaded []v1alpha1.AtlasMappingPodStatus) []v1alpha1.AtlasMappingPodStatus {
+	var current []v1alpha1.AtlasMappingPodStatus
+	for i := range pods {
+		if status := PodStatus(&pods[i]); containsPodStatus(loaded, status) {
+			current = append(current, status)
+		}
+	}
+	return current
+}
+
+func containsPodStatus(statuses []v1alpha1.AtlasMappingPodStatus, status v1alpha1.AtlasMappingPodStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// PodTarget is the AtlasMap REST API of the pod. Pods serving HTTPS, as their readiness probe tells, present the
+// serving certificate of the AtlasMap Service, so that its host name is verified
+func PodTarget(pod *corev1.Pod, atlasMap *v1alpha1.AtlasMap) Target {
+	scheme := "http"
+	port := int32(defaultPort)
+	for _, container := range pod.Spec.Containers {
+		if container.Name != atlasMapContainer {
+			continue
+		}
+		for _, p := range container.Ports {
+			if p.Name == httpPortName {
+				port = p.ContainerPort
+			}
+		}
+		if probe := container.ReadinessProbe; probe != nil && probe.HTTPGet != nil && probe.HTTPGet.Scheme == corev1.URISchemeHTTPS {
+			scheme = "https"
+		}
+	}
+
+	target := Target{URL: fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port))))}
+	if scheme == "https" {
+		target.ServerName = fmt.Sprintf("%s.%s.svc", atlasMap.Name, atlasMap.Namespace)
+	}
+	return target
+}
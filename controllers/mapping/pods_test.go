@@ -0,0 +1,138 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/atlasmap/atlasmap-operator/controllers/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newPod(name string, ready bool, restartCount int32, scheme corev1.URIScheme) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "test", UID: types.UID(name + "-uid")},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "atlasmap",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8585}, {Name: "jolokia", ContainerPort: 8778}},
+					ReadinessProbe: &corev1.Probe{
+						Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Scheme: scheme}},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			PodIP:             "10.0.0.1",
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "atlasmap", RestartCount: restartCount}},
+		},
+	}
+}
+
+func TestReadyPods(t *testing.T) {
+	deleted := newPod("deleted", true, 0, corev1.URISchemeHTTP)
+	deleted.DeletionTimestamp = &v1.Time{}
+	unscheduled := newPod("unscheduled", true, 0, corev1.URISchemeHTTP)
+	unscheduled.Status.PodIP = ""
+
+	ready := ReadyPods([]corev1.Pod{newPod("ready", true, 0, corev1.URISchemeHTTP), newPod("starting", false, 0, corev1.URISchemeHTTP), deleted, unscheduled})
+	if assert.Len(t, ready, 1) {
+		assert.Equal(t, "ready", ready[0].Name)
+	}
+}
+
+func TestPendingPods(t *testing.T) {
+	pods := []corev1.Pod{newPod("a", true, 0, corev1.URISchemeHTTP), newPod("b", true, 1, corev1.URISchemeHTTP)}
+	loaded := []v1alpha1.AtlasMappingPodStatus{
+		{Name: "a", UID: "a-uid"},
+		// The container restarted since the mapping was loaded
+		{Name: "b", UID: "b-uid"},
+		// The pod was replaced
+		{Name: "c", UID: "c-uid"},
+	}
+
+	pending := PendingPods(pods, loaded)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "b", pending[0].Name)
+	}
+	assert.Equal(t, []v1alpha1.AtlasMappingPodStatus{{Name: "a", UID: "a-uid"}}, CurrentPodStatuses(pods, loaded))
+}
+
+func TestPodTarget(t *testing.T) {
+	atlasMap := &v1alpha1.AtlasMap{ObjectMeta: v1.ObjectMeta{Name: "atlasmap", Namespace: "test"}}
+
+	pod := newPod("a", true, 0, corev1.URISchemeHTTP)
+	assert.Equal(t, Target{URL: "http://10.0.0.1:8585"}, PodTarget(&pod, atlasMap))
+
+	pod = newPod("a", true, 0, corev1.URISchemeHTTPS)
+	assert.Equal(t, Target{URL: "https://10.0.0.1:8585", ServerName: "atlasmap.test.svc"}, PodTarget(&pod, atlasMap))
+}
+
+func TestLoaderJob(t *testing.T) {
+	atlasMap := &v1alpha1.AtlasMap{
+		ObjectMeta: v1.ObjectMeta{Name: "atlasmap", Namespace: "test"},
+		Spec:       v1alpha1.AtlasMapSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "mirror"}}},
+	}
+	atlasMapping := &v1alpha1.AtlasMapping{
+		ObjectMeta: v1.ObjectMeta{Name: "mapping", Namespace: "test"},
+		Spec: v1alpha1.AtlasMappingSpec{
+			AtlasMapName: "atlasmap",
+			Source:       v1alpha1.AtlasMappingSource{PersistentVolumeClaim: &v1alpha1.AtlasMappingVolumeSource{ClaimName: "mappings", Path: "orders/mapping.adm"}},
+		},
+	}
+	pods := []corev1.Pod{newPod("a", true, 0, corev1.URISchemeHTTP), newPod("b", true, 2, corev1.URISchemeHTTPS)}
+
+	job, err := LoaderJob(atlasMapping, atlasMap, pods, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, "mapping-loader", job.Name)
+	assert.Equal(t, "hash", job.Annotations[JobContentHashAnnotation])
+	assert.NotEqual(t, "atlasmap", job.Labels["app.kubernetes.io/name"])
+
+	loaded, err := JobPods(job)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.AtlasMappingPodStatus{{Name: "a", UID: "a-uid"}, {Name: "b", UID: "b-uid", RestartCount: 2}}, loaded)
+
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, "mappings", podSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	container := podSpec.Containers[0]
+	assert.Equal(t, config.DefaultConfiguration.MappingLoaderImage, container.Image)
+	assert.Equal(t, atlasMap.Spec.ImagePullSecrets, podSpec.ImagePullSecrets)
+	assert.Equal(t, "/mapping/orders/mapping.adm", container.Env[0].Value)
+
+	script := strings.Split(container.Command[2], "\n")
+	if assert.Len(t, script, 3) {
+		assert.Contains(t, script[1], `--data-binary "@$MAPPING_FILE"`)
+		assert.Contains(t, script[1], "'http://10.0.0.1:8585/v2/atlas/mapping/ZIP/0'")
+		assert.Contains(t, script[2], "--resolve 'atlasmap.test.svc:8585:10.0.0.1'")
+		assert.Contains(t, script[2], "'https://atlasmap.test.svc:8585/v2/atlas/mapping/ZIP/0'")
+	}
+}
+
+func TestCurlCommand(t *testing.T) {
+	atlasMapping := &v1alpha1.AtlasMapping{Spec: v1alpha1.AtlasMappingSpec{Format: v1alpha1.AtlasMappingFormatJSON, DefinitionID: 3}}
+
+	command, err := curlCommand(Target{URL: "http://10.0.0.1:8585"}, atlasMapping)
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --fail --silent --show-error -X PUT -H 'Content-Type: application/json' --data-binary "@$MAPPING_FILE" `+
+		`'http://10.0.0.1:8585/v2/atlas/mapping/JSON/3'`, command)
+
+	// HTTPS pods are reached through the host name of their certificate, resolved to the pod address
+	command, err = curlCommand(Target{URL: "https://[fd00::1]:8585", ServerName: "atlasmap.test.svc"}, atlasMapping)
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --fail --silent --show-error -X PUT -H 'Content-Type: application/json' --data-binary "@$MAPPING_FILE" `+
+		`--cacert '`+ServiceCAFile+`' --resolve 'atlasmap.test.svc:8585:[fd00::1]' 'https://atlasmap.test.svc:8585/v2/atlas/mapping/JSON/3'`, command)
+
+	_, err = curlCommand(Target{URL: "https://10.0.0.1", ServerName: "atlasmap.test.svc"}, atlasMapping)
+	assert.Error(t, err)
+
+	assert.Equal(t, `'it'\''s $HOME'`, shellQuote("it's $HOME"))
+}
@@ -0,0 +1,110 @@
+package mapping
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SourceNotFoundError is returned when the object or key holding the mapping content does not exist
+type SourceNotFoundError struct {
+	Message string
+}
+
+func (e *SourceNotFoundError) Error() string {
+	return e.Message
+}
+
+// ValidateSource checks that exactly one source of the mapping content is set, and that a volume path stays within the volume
+func ValidateSource(source v1alpha1.AtlasMappingSource) error {
+	count := 0
+	if source.ConfigMap != nil {
+		count++
+	}
+	if source.Secret != nil {
+		count++
+	}
+	if source.PersistentVolumeClaim != nil {
+		count++
+	}
+	if count != 1 {
+		return fmt.Errorf("exactly one of source.configMap, source.secret and source.persistentVolumeClaim must be set")
+	}
+	// The path must not escape the volume
+	if claim := source.PersistentVolumeClaim; claim != nil {
+		if path.IsAbs(claim.Path) {
+			return fmt.Errorf("source.persistentVolumeClaim.path must be relative to the root of the volume")
+		}
+		for _, segment := range strings.Split(claim.Path, "/") {
+			if segment == ".." {
+				return fmt.Errorf("source.persistentVolumeClaim.path must not contain '..' segments")
+			}
+		}
+	}
+	return nil
+}
+
+// ReadContent reads the mapping content from the ConfigMap or Secret of the AtlasMapping. Content stored on a
+// PersistentVolumeClaim cannot be read by the operator and is loaded by a Job instead
+func ReadContent(ctx context.Context, reader client.Reader, atlasMapping *v1alpha1.AtlasMapping) ([]byte, error) {
+	source := atlasMapping.Spec.Source
+	switch {
+	case source.ConfigMap != nil:
+		configMap := &corev1.ConfigMap{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: source.ConfigMap.Name, Namespace: atlasMapping.Namespace}, configMap); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, &SourceNotFoundError{fmt.Sprintf("ConfigMap %s not found", source.ConfigMap.Name)}
+			}
+			return nil, err
+		}
+		if content, ok := configMap.BinaryData[source.ConfigMap.Key]; ok {
+			return content, nil
+		}
+		if content, ok := configMap.Data[source.ConfigMap.Key]; ok {
+			return []byte(content), nil
+		}
+		return nil, &SourceNotFoundError{fmt.Sprintf("Key %s not found in ConfigMap %s", source.ConfigMap.Key, source.ConfigMap.Name)}
+	case source.Secret != nil:
+		secret := &corev1.Secret{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: source.Secret.Name, Namespace: atlasMapping.Namespace}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, &SourceNotFoundError{fmt.Sprintf("Secret %s not found", source.Secret.Name)}
+			}
+			return nil, err
+		}
+		if content, ok := secret.Data[source.Secret.Key]; ok {
+			return content, nil
+		}
+		return nil, &SourceNotFoundError{fmt.Sprintf("Key %s not found in Secret %s", source.Secret.Key, source.Secret.Name)}
+	}
+	return nil, nil
+}
+
+// ContentHash hashes the mapping content together with the settings it is loaded with. For content stored on a
+// PersistentVolumeClaim, which the operator cannot read, the location of the file is hashed instead
+func ContentHash(atlasMapping *v1alpha1.AtlasMapping, content []byte) string {
+	hash := sha256.New()
+	write := func(value string) {
+		_, _ = hash.Write([]byte(value))
+		_, _ = hash.Write([]byte{0})
+	}
+	write(string(atlasMapping.MappingFormat()))
+	write(strconv.Itoa(int(atlasMapping.Spec.DefinitionID)))
+	if claim := atlasMapping.Spec.Source.PersistentVolumeClaim; claim != nil {
+		write(claim.ClaimName)
+		write(claim.Path)
+	} else {
+		_, _ = hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
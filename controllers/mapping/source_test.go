@@ -0,0 +1,85 @@
+package mapping
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateSource(t *testing.T) {
+	selector := &v1alpha1.AtlasMappingKeySelector{Name: "mapping", Key: "mapping.adm"}
+
+	assert.Error(t, ValidateSource(v1alpha1.AtlasMappingSource{}))
+	assert.NoError(t, ValidateSource(v1alpha1.AtlasMappingSource{ConfigMap: selector}))
+	assert.NoError(t, ValidateSource(v1alpha1.AtlasMappingSource{Secret: selector}))
+	assert.NoError(t, ValidateSource(v1alpha1.AtlasMappingSource{PersistentVolumeClaim: &v1alpha1.AtlasMappingVolumeSource{ClaimName: "mappings", Path: "mapping.adm"}}))
+	assert.Error(t, ValidateSource(v1alpha1.AtlasMappingSource{ConfigMap: selector, Secret: selector}))
+
+	for _, path := range []string{"../mapping.adm", "orders/../../mapping.adm", "/etc/passwd", ".."} {
+		assert.Error(t, ValidateSource(v1alpha1.AtlasMappingSource{PersistentVolumeClaim: &v1alpha1.AtlasMappingVolumeSource{ClaimName: "mappings", Path: path}}), path)
+	}
+	assert.NoError(t, ValidateSource(v1alpha1.AtlasMappingSource{PersistentVolumeClaim: &v1alpha1.AtlasMappingVolumeSource{ClaimName: "mappings", Path: "orders/..mapping.adm"}}))
+}
+
+func TestReadContent(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: "mappings", Namespace: "test"},
+			Data:       map[string]string{"mapping.json": `{"AtlasMapping":{}}`},
+			BinaryData: map[string][]byte{"mapping.adm": {0x50, 0x4b}},
+		},
+		&corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Name: "mappings", Namespace: "test"},
+			Data:       map[string][]byte{"mapping.adm": {0x50, 0x4b, 0x03}},
+		},
+	).Build()
+
+	newAtlasMapping := func(source v1alpha1.AtlasMappingSource) *v1alpha1.AtlasMapping {
+		return &v1alpha1.AtlasMapping{
+			ObjectMeta: v1.ObjectMeta{Name: "mapping", Namespace: "test"},
+			Spec:       v1alpha1.AtlasMappingSpec{AtlasMapName: "atlasmap", Source: source},
+		}
+	}
+
+	content, err := ReadContent(context.TODO(), c, newAtlasMapping(v1alpha1.AtlasMappingSource{ConfigMap: &v1alpha1.AtlasMappingKeySelector{Name: "mappings", Key: "mapping.json"}}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"AtlasMapping":{}}`, string(content))
+
+	content, err = ReadContent(context.TODO(), c, newAtlasMapping(v1alpha1.AtlasMappingSource{ConfigMap: &v1alpha1.AtlasMappingKeySelector{Name: "mappings", Key: "mapping.adm"}}))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x50, 0x4b}, content)
+
+	content, err = ReadContent(context.TODO(), c, newAtlasMapping(v1alpha1.AtlasMappingSource{Secret: &v1alpha1.AtlasMappingKeySelector{Name: "mappings", Key: "mapping.adm"}}))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x50, 0x4b, 0x03}, content)
+
+	_, err = ReadContent(context.TODO(), c, newAtlasMapping(v1alpha1.AtlasMappingSource{ConfigMap: &v1alpha1.AtlasMappingKeySelector{Name: "mappings", Key: "missing"}}))
+	assert.IsType(t, &SourceNotFoundError{}, err)
+
+	_, err = ReadContent(context.TODO(), c, newAtlasMapping(v1alpha1.AtlasMappingSource{Secret: &v1alpha1.AtlasMappingKeySelector{Name: "missing", Key: "mapping.adm"}}))
+	assert.IsType(t, &SourceNotFoundError{}, err)
+}
+
+func TestContentHash(t *testing.T) {
+	atlasMapping := &v1alpha1.AtlasMapping{
+		Spec: v1alpha1.AtlasMappingSpec{
+			AtlasMapName: "atlasmap",
+			Source:       v1alpha1.AtlasMappingSource{ConfigMap: &v1alpha1.AtlasMappingKeySelector{Name: "mappings", Key: "mapping.adm"}},
+		},
+	}
+	hash := ContentHash(atlasMapping, []byte("v1"))
+	assert.Equal(t, hash, ContentHash(atlasMapping, []byte("v1")))
+	assert.NotEqual(t, hash, ContentHash(atlasMapping, []byte("v2")))
+
+	// The mapping is stored again when it is loaded as another definition or format
+	atlasMapping.Spec.DefinitionID = 1
+	assert.NotEqual(t, hash, ContentHash(atlasMapping, []byte("v1")))
+	atlasMapping.Spec.DefinitionID = 0
+	atlasMapping.Spec.Format = v1alpha1.AtlasMappingFormatJSON
+	assert.NotEqual(t, hash, ContentHash(atlasMapping, []byte("v1")))
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type writeCounterKey struct{}
+
+// WithWriteCounter returns a context in which the API writes made through a counting client are
+// counted, together with the counter
+func WithWriteCounter(ctx context.Context) (context.Context, *int64) {
+	counter := new(int64)
+	return context.WithValue(ctx, writeCounterKey{}, counter), counter
+}
+
+func countWrite(ctx context.Context) {
+	if counter, ok := ctx.Value(writeCounterKey{}).(*int64); ok {
+		atomic.AddInt64(counter, 1)
+	}
+}
+
+// NewCountingClient wraps a client so that its writes are counted by the counter of the request context
+func NewCountingClient(c client.Client) client.Client {
+	return &countingClient{c}
+}
+
+type countingClient struct {
+	client.Client
+}
+
+func (c *countingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	countWrite(ctx)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *countingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	countWrite(ctx)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *countingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	countWrite(ctx)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *countingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	countWrite(ctx)
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *countingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	countWrite(ctx)
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *countingClient) Status() client.StatusWriter {
+	return &countingStatusWriter{c.Client.Status()}
+}
+
+type countingStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *countingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	countWrite(ctx)
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func (w *countingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	countWrite(ctx)
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
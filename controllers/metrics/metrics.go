@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "atlasmap_operator"
+
+var (
+	// ActionDuration measures how long each operator action takes to handle an AtlasMap
+	ActionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "action_duration_seconds",
+		Help:      "Time taken by an operator action to reconcile an AtlasMap",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"action"})
+
+	// ActionFailures counts the errors returned by each operator action
+	ActionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "action_failures_total",
+		Help:      "Number of errors returned by an operator action",
+	}, []string{"action"})
+
+	// ReconcileWrites measures the number of API writes made while reconciling an AtlasMap
+	ReconcileWrites = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_api_writes",
+		Help:      "Number of create, update, patch and delete requests made by a single AtlasMap reconcile",
+		Buckets:   []float64{0, 1, 2, 3, 5, 8, 13, 21},
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(ActionDuration, ActionFailures, ReconcileWrites)
+}
+
+// ObserveAction records the duration and outcome of an operator action
+func ObserveAction(action string, start time.Time, err error) {
+	ActionDuration.WithLabelValues(action).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ActionFailures.WithLabelValues(action).Inc()
+	}
+}
+
+// RegisterFleetCollector registers a collector that reports the AtlasMaps known to the given reader,
+// typically the manager cache, each time the metrics are scraped
+func RegisterFleetCollector(reader client.Reader) error {
+	return metrics.Registry.Register(&fleetCollector{reader: reader})
+}
+
+var atlasMapsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "atlasmaps"),
+	"Number of AtlasMap resources by namespace, phase and version",
+	[]string{"namespace", "phase", "version"}, nil,
+)
+
+type fleetCollector struct {
+	reader client.Reader
+}
+
+type fleetKey struct {
+	namespace string
+	phase     string
+	version   string
+}
+
+func (c *fleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- atlasMapsDesc
+}
+
+func (c *fleetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list := &v1alpha1.AtlasMapList{}
+	if err := c.reader.List(ctx, list); err != nil {
+		ch <- prometheus.NewInvalidMetric(atlasMapsDesc, err)
+		return
+	}
+
+	counts := make(map[fleetKey]int)
+	for _, atlasMap := range list.Items {
+		version := atlasMap.Status.Version
+		if len(version) == 0 {
+			version = atlasMap.Spec.Version
+		}
+		counts[fleetKey{atlasMap.Namespace, string(atlasMap.Status.Phase), version}]++
+	}
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(atlasMapsDesc, prometheus.GaugeValue, float64(count), key.namespace, key.phase, key.version)
+	}
+}
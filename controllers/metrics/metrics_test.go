@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCountingClient(t *testing.T) {
+	c := NewCountingClient(fake.NewClientBuilder().Build())
+	ctx, writes := WithWriteCounter(context.TODO())
+
+	configMap := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "test", Namespace: "test"}}
+	assert.NoError(t, c.Create(ctx, configMap))
+	assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(configMap), configMap))
+	assert.NoError(t, c.Update(ctx, configMap))
+	assert.NoError(t, c.Delete(ctx, configMap))
+	assert.Equal(t, int64(3), *writes)
+
+	// Writes outside of a reconcile are not counted
+	assert.NoError(t, c.Create(context.TODO(), &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "test"}}))
+	assert.Equal(t, int64(3), *writes)
+}
+
+func TestFleetCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newAtlasMap := func(namespace string, name string, phase v1alpha1.AtlasMapPhase) *v1alpha1.AtlasMap {
+		return &v1alpha1.AtlasMap{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1alpha1.AtlasMapSpec{Version: "2.3.0"},
+			Status:     v1alpha1.AtlasMapStatus{Phase: phase},
+		}
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newAtlasMap("a", "one", v1alpha1.AtlasMapPhasePhaseDeployed),
+		newAtlasMap("a", "two", v1alpha1.AtlasMapPhasePhaseDeployed),
+		newAtlasMap("b", "three", v1alpha1.AtlasMapPhasePhaseDeploying),
+	).Build()
+
+	// AtlasMaps are counted by their deployed version
+	latest := newAtlasMap("a", "four", v1alpha1.AtlasMapPhasePhaseDeployed)
+	latest.Spec.Version = "latest"
+	latest.Status.Version = "2.3.0"
+	assert.NoError(t, reader.Create(context.TODO(), latest))
+
+	collector := &fleetCollector{reader: reader}
+	assert.Equal(t, 2, testutil.CollectAndCount(collector))
+}
@@ -1,86 +1,13 @@
 package util
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"strings"
 
-	"github.com/Masterminds/semver"
 	"github.com/atlasmap/atlasmap-operator/api/v1alpha1"
-	configv1client "github.com/openshift/client-go/config/clientset/versioned"
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/rest"
-	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-var log = logf.Log.WithName("util")
-
-// IsOpenShift returns true if the platform cluster is OpenShift
-func IsOpenShift(config *rest.Config) (bool, error) {
-	client, err := discovery.NewDiscoveryClientForConfig(config)
-	if err != nil {
-		return false, err
-	}
-
-	_, err = client.ServerResourcesForGroupVersion("route.openshift.io/v1")
-
-	if err != nil && errors.IsNotFound(err) {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-	return true, nil
-}
-
-// GetClusterVersionSemVer gets the semantic version for the OpenShift cluster
-func getClusterVersionSemVer(config *rest.Config) *semver.Version {
-	configClient, err := configv1client.NewForConfig(config)
-	ctx := context.TODO()
-
-	if err != nil {
-		log.Error(err, "Failed to create config client")
-		return nil
-	}
-
-	var openShiftSemVer *semver.Version
-	clusterVersion, err := configClient.
-		ConfigV1().
-		ClusterVersions().
-		Get(ctx, "version", metav1.GetOptions{})
-
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// default to OpenShift 3 as ClusterVersion API was introduced in OpenShift 4
-			openShiftSemVer, _ = semver.NewVersion("3")
-		} else {
-			log.Error(err, "Failed to get OpenShift cluster version")
-			return nil
-		}
-	} else {
-		//latest version from the history
-		v := clusterVersion.Status.History[0].Version
-		openShiftSemVer, err = semver.NewVersion(v)
-		if err != nil {
-			log.Error(err, "Failed to get OpenShift cluster version")
-			return nil
-		}
-	}
-	return openShiftSemVer
-}
-
-// IsOpenShift43Plus returns true if the cluster version is OpenShift >= 4.3
-func IsOpenShift43Plus(config *rest.Config) bool {
-	openShiftSemVer := getClusterVersionSemVer(config)
-	if openShiftSemVer != nil {
-		constraint43, _ := semver.NewConstraint(">= 4.3")
-		return constraint43.Check(openShiftSemVer)
-	}
-	return false
-}
-
 // GetIngressHostNameFor generates a host name for the Ingress host
 func GetIngressHostNameFor(atlasMap *v1alpha1.AtlasMap) string {
 	hostName := atlasMap.Spec.RouteHostName
@@ -95,6 +22,26 @@ func ImageName(image string, tag string) string {
 	return fmt.Sprintf("%s:%s", image, tag)
 }
 
+// ParseImage splits a container image reference into its repository, tag and digest
+func ParseImage(image string) (repository string, tag string, digest string) {
+	repository = image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		digest = repository[i+1:]
+		repository = repository[:i]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		tag = repository[i+1:]
+		repository = repository[:i]
+	}
+	return repository, tag, digest
+}
+
+// Labels identifying the AtlasMap that a cluster-scoped ConsoleLink belongs to
+const (
+	ConsoleLinkNameLabel      = "atlasmap.io/name"
+	ConsoleLinkNamespaceLabel = "atlasmap.io/namespace"
+)
+
 // ConsoleLinkName generates a name for an OpenShift ConsoleLink
 func ConsoleLinkName(atlasMap *v1alpha1.AtlasMap) string {
 	return atlasMap.Name + "-" + atlasMap.Namespace
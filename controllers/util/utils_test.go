@@ -26,6 +26,23 @@ func TestImageName(t *testing.T) {
 	assert.Equal(t, image, "docker.io/test/image:1.2.3")
 }
 
+func TestParseImage(t *testing.T) {
+	repository, tag, digest := ParseImage("docker.io/test/image:1.2.3")
+	assert.Equal(t, "docker.io/test/image", repository)
+	assert.Equal(t, "1.2.3", tag)
+	assert.Equal(t, "", digest)
+
+	repository, tag, digest = ParseImage("registry.local:5000/test/image@sha256:abc123")
+	assert.Equal(t, "registry.local:5000/test/image", repository)
+	assert.Equal(t, "", tag)
+	assert.Equal(t, "sha256:abc123", digest)
+
+	repository, tag, digest = ParseImage("registry.local:5000/test/image")
+	assert.Equal(t, "registry.local:5000/test/image", repository)
+	assert.Equal(t, "", tag)
+	assert.Equal(t, "", digest)
+}
+
 func TestConsoleLinkName(t *testing.T) {
 	atlasMap := &v1alpha1.AtlasMap{
 		ObjectMeta: v1.ObjectMeta{
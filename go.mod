@@ -7,6 +7,7 @@ require (
 	github.com/onsi/gomega v1.13.0
 	github.com/openshift/api v0.0.0-20210901140736-d8ed1449662d
 	github.com/openshift/client-go v0.0.0-20210831095141-e19a065e79f7
+	github.com/prometheus/client_golang v1.11.0
 	github.com/stretchr/testify v1.7.0
 	k8s.io/api v0.22.1
 	k8s.io/apimachinery v0.22.1
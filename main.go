@@ -33,6 +33,12 @@ import (
 
 	atlasmapiov1alpha1 "github.com/atlasmap/atlasmap-operator/api/v1alpha1"
 	"github.com/atlasmap/atlasmap-operator/controllers"
+	"github.com/atlasmap/atlasmap-operator/controllers/capabilities"
+	"github.com/atlasmap/atlasmap-operator/controllers/config"
+	"github.com/atlasmap/atlasmap-operator/controllers/mapping"
+	"github.com/atlasmap/atlasmap-operator/controllers/metrics"
+	"github.com/atlasmap/atlasmap-operator/controllers/util"
+	consolev1 "github.com/openshift/api/console/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	//+kubebuilder:scaffold:imports
 )
@@ -46,6 +52,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(atlasmapiov1alpha1.AddToScheme(scheme))
 	utilruntime.Must(routev1.AddToScheme(scheme))
+	utilruntime.Must(consolev1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -53,11 +60,20 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var atlasMapImageName string
+	var atlasMapImageVersion string
+	var mappingLoaderImage string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&atlasMapImageName, "atlasmap-image-name", util.GetEnvVar(config.AtlasMapImageNameEnv, ""),
+		"The default AtlasMap container image name. Overrides "+config.RelatedImageEnv+".")
+	flag.StringVar(&atlasMapImageVersion, "atlasmap-image-version", util.GetEnvVar(config.AtlasMapImageVersionEnv, ""),
+		"The default AtlasMap container image version. Overrides "+config.RelatedImageEnv+".")
+	flag.StringVar(&mappingLoaderImage, "mapping-loader-image", util.GetEnvVar(config.MappingLoaderImageEnv, config.DefaultConfiguration.MappingLoaderImage),
+		"The image of the Jobs loading mappings from volumes, which must provide a shell and curl.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -66,6 +82,9 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	config.DefaultConfiguration.ApplyOverrides(atlasMapImageName, atlasMapImageVersion, util.GetEnvVar(config.RelatedImageEnv, ""))
+	config.DefaultConfiguration.MappingLoaderImage = mappingLoaderImage
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -73,19 +92,56 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "4f1bd35c.atlasmap.io",
+		NewCache:               controllers.NewCache,
+		ClientDisableCacheFor:  controllers.UncachedObjects,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	clusterCapabilities, err := capabilities.NewDetector(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create cluster capabilities detector")
+		os.Exit(1)
+	}
+	if err := mgr.Add(clusterCapabilities); err != nil {
+		setupLog.Error(err, "unable to refresh cluster capabilities")
+		os.Exit(1)
+	}
+	if err := mgr.AddMetricsExtraHandler("/capabilities", clusterCapabilities); err != nil {
+		setupLog.Error(err, "unable to serve cluster capabilities")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.AtlasMapReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:       metrics.NewCountingClient(mgr.GetClient()),
+		Scheme:       mgr.GetScheme(),
+		Capabilities: clusterCapabilities,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AtlasMap")
 		os.Exit(1)
 	}
+	mappingLoader, err := mapping.NewLoader()
+	if err != nil {
+		setupLog.Error(err, "unable to create mapping loader")
+		os.Exit(1)
+	}
+	if err = (&controllers.AtlasMappingReconciler{
+		Client: metrics.NewCountingClient(mgr.GetClient()),
+		Scheme: mgr.GetScheme(),
+		Loader: mappingLoader,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AtlasMapping")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		atlasmapiov1alpha1.DefaultVersion = config.DefaultConfiguration.Version
+		if err = (&atlasmapiov1alpha1.AtlasMap{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AtlasMap")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {